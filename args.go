@@ -0,0 +1,86 @@
+package errors
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NamedArgs describes the args of a function by name. It is the ArgStringer()
+// of errors made by a Builder from NewArgsBuilder.
+type NamedArgs struct {
+	text string
+	args []Field
+}
+
+// NewNamedArgs takes alternating names and values, and formats them like
+// `user="alice", items=[3]`. Strings are quoted, and other values are
+// formatted with %v.
+func NewNamedArgs(keyvals ...interface{}) NamedArgs {
+	args := appendFields(nil, keyvals)
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		format := "%s=%v"
+		if _, ok := arg.Value.(string); ok {
+			format = "%s=%q"
+		}
+		parts = append(parts, fmt.Sprintf(format, arg.Key, arg.Value))
+	}
+	return NamedArgs{strings.Join(parts, ", "), args}
+}
+
+// String returns the args as they were formatted by NewNamedArgs.
+func (na NamedArgs) String() string {
+	return na.text
+}
+
+// Args returns the names and values of the args, for structured encoders.
+// The values are the ones given to NewNamedArgs, and might have changed since
+// they were formatted.
+func (na NamedArgs) Args() []Field {
+	return na.args
+}
+
+// NewArgsBuilder is like NewBuilder, except that the args are given as
+// alternating names and values, which keeps them in sync with the parameters
+// of the function:
+//
+//	b := errors.NewArgsBuilder("user", user, "pw", errors.Redacted, "items", errors.Len(items))
+//
+// StackString shows them as `(user="alice", pw=<redacted>, items=[3])`. The
+// args are formatted right away, like NewBuilder does, and the values are
+// also kept for structured encoders; see NamedArgs.
+func NewArgsBuilder(keyvals ...interface{}) FieldBuilder {
+	ab := new(argsBuilder)
+	ab.argStringer = NewNamedArgs(keyvals...)
+	return ab
+}
+
+// Redacted stands in for an arg whose value must not be shown.
+var Redacted = Secret(nil)
+
+// Length is the length of an arg, shown as "[3]" instead of the whole value.
+// Unknown lengths are -1, shown as "[?]".
+type Length int
+
+// Len returns the length of a slice, array, map, channel or string, or of
+// a pointer to an array, for brevity in the args of a Builder. For other
+// values, it returns -1.
+func Len(v interface{}) Length {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr && val.Type().Elem().Kind() == reflect.Array {
+		return Length(val.Type().Elem().Len())
+	}
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan, reflect.String:
+		return Length(val.Len())
+	}
+	return -1
+}
+
+func (l Length) String() string {
+	if l < 0 {
+		return "[?]"
+	}
+	return fmt.Sprintf("[%d]", int(l))
+}
@@ -0,0 +1,67 @@
+package errors
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewArgsBuilder(t *testing.T) {
+	items := []int{1, 2, 3}
+	b := NewArgsBuilder("user", "alice", "pw", Redacted, "items", Len(items), "n", 5)
+	items = append(items, 4)
+	err := b.Errorf("denied")
+	assert.Regexp(
+		t,
+		`\.TestNewArgsBuilder\(user="alice", pw=<redacted>, items=\[3\], n=5\) args_test\.go:[0-9]+ denied$`,
+		StackString(err),
+	)
+
+	loc := Outermost(err).(interface {
+		ArgStringer() interface{ String() string }
+	})
+	args := loc.ArgStringer().(NamedArgs).Args()
+	assert.Equal(t, []Field{
+		{"user", "alice"},
+		{"pw", Redacted},
+		{"items", Length(3)},
+		{"n", 5},
+	}, args)
+}
+
+func TestLen(t *testing.T) {
+	assert.Equal(t, Length(2), Len([]string{"a", "b"}))
+	assert.Equal(t, Length(1), Len(map[int]int{1: 1}))
+	assert.Equal(t, Length(3), Len("abc"))
+	assert.Equal(t, Length(4), Len(new([4]byte)))
+	assert.Equal(t, Length(0), Len([]int(nil)))
+	assert.Equal(t, Length(-1), Len(5))
+	assert.Equal(t, Length(-1), Len(nil))
+	assert.Equal(t, "[2]", Len("ab").String())
+	assert.Equal(t, "[?]", Len(nil).String())
+}
+
+func TestNamedArgsJSON(t *testing.T) {
+	orig := NewArgsBuilder("user", "alice", "pw", Redacted, "items", Len([]int{1})).
+		Errorf("denied")
+	buf, err := MarshalChain(orig)
+	require.NoError(t, err)
+	var layers []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &layers))
+	assert.Equal(t, `user="alice", pw=<redacted>, items=[1]`, layers[0]["args"])
+	assert.Equal(
+		t,
+		map[string]interface{}{"user": "alice", "pw": "<redacted>", "items": float64(1)},
+		layers[0]["named_args"],
+	)
+
+	chain, err := UnmarshalChain(buf)
+	require.NoError(t, err)
+	assert.Equal(t, StackString(orig), StackString(chain))
+	args := Outermost(chain).(interface {
+		ArgStringer() interface{ String() string }
+	}).ArgStringer().(NamedArgs).Args()
+	assert.Equal(t, Field{"user", "alice"}, args[0])
+}
@@ -0,0 +1,60 @@
+package errors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// backportIs and backportAs call the implementations of Is and As for Go
+// versions before 1.13, whatever the version running the tests.
+func backportIs(err, target error) bool {
+	if target == nil {
+		return err == target
+	}
+	return is(err, target, reflect.TypeOf(target).Comparable())
+}
+
+func backportAs(err error, target interface{}) bool {
+	val := reflect.ValueOf(target)
+	return as(err, target, val, val.Type().Elem())
+}
+
+// holderError is comparable according to reflect, but comparing two of them
+// panics if they hold non-comparable errors.
+type holderError struct {
+	err error
+}
+
+func (he holderError) Error() string { return "holder" }
+
+func TestBackportIsNonComparable(t *testing.T) {
+	g := Group{New("a")}
+	assert.NotPanics(t, func() {
+		assert.False(t, backportIs(holderError{g}, holderError{g}))
+	})
+	token := holderError{New("b")}
+	assert.True(t, backportIs(Wrap(token, "c"), token))
+	assert.True(t, backportIs(WrapWith(g, g), g))
+	assert.True(t, backportIs(Group{WrapWith(g, g)}, g))
+	assert.False(t, backportIs(Group{nil}, token))
+	assert.True(t, backportIs(nil, nil))
+}
+
+func TestBackportIsAs(t *testing.T) {
+	errTimeout := New("timeout")
+	te := new(testError)
+	multi := &multiError{"multi", []error{New("a"), Wrap(errTimeout, "b"), te}}
+	assert.True(t, backportIs(Wrap(multi, "outer"), errTimeout))
+	assert.False(t, backportIs(multi, New("timeout")))
+	assert.True(t, backportIs(Wrap(Group{New("refused"), multi}, "all"), errTimeout))
+
+	var target *testError
+	assert.True(t, backportAs(Wrap(multi, "outer"), &target))
+	assert.Equal(t, te, target)
+	var wrongTarget *holderError
+	assert.False(t, backportAs(multi, &wrongTarget))
+	var loc Located
+	assert.True(t, backportAs(NewBuilder("").Wrap(multi, "located"), &loc))
+}
@@ -2,6 +2,9 @@ package errors
 
 import (
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 )
 
 // Builder implementors can make and wrap errors.
@@ -10,12 +13,34 @@ type Builder interface {
 	Wrap(err error, msg string, args ...interface{}) Wrapped
 }
 
+// FieldBuilder is a Builder that can attach key/value fields to its errors.
+// The builders of this package are FieldBuilders. Use WithFields to attach
+// fields with any Builder.
+type FieldBuilder interface {
+	Builder
+
+	// With returns a FieldBuilder whose errors also have the given
+	// alternating keys and values, as reported by Fields.
+	With(keyvals ...interface{}) FieldBuilder
+}
+
+// WithFields returns a FieldBuilder whose errors are made by b, and also have
+// the given alternating keys and values, as reported by Fields. It calls
+// b.With if b is a FieldBuilder.
+func WithFields(b Builder, keyvals ...interface{}) FieldBuilder {
+	if fb, ok := b.(FieldBuilder); ok {
+		return fb.With(keyvals...)
+	}
+	return &fieldsBuilder{b, appendFields(nil, keyvals)}
+}
+
 type builtinBuilder struct{}
 
 // BuiltinBuilder has no frills. It is a proxy to built-in go packages.
 var BuiltinBuilder Builder = (*builtinBuilder)(nil)
 
-// Errorf is the same as fmt.Errorf
+// Errorf is the same as fmt.Errorf. Before Go 1.13, fmt.Errorf does not know
+// the %w verb, so it neither wraps its operand nor formats it.
 func (bb *builtinBuilder) Errorf(msg string, args ...interface{}) error {
 	return fmt.Errorf(msg, args...)
 }
@@ -29,6 +54,11 @@ func (bb *builtinBuilder) Wrap(
 	return Wrap(err, msg, args...)
 }
 
+// With returns a Builder whose errors also have the given key/value pairs.
+func (bb *builtinBuilder) With(keyvals ...interface{}) FieldBuilder {
+	return &fieldsBuilder{bb, appendFields(nil, keyvals)}
+}
+
 // signatured is an error that also has info about the function where it
 // happened. It behaves like an error created with the builtin errors.New,
 // except when processed with a function that is aware of its extra methods,
@@ -37,6 +67,14 @@ type signatured struct {
 	// message describes the error
 	message string
 
+	// short is the message without the text of cause, if StackString shows
+	// cause as the next layer.
+	short string
+
+	// cause is the operand of the %w verb in the message format, if any, or a
+	// Group of the operands if there are several.
+	cause error
+
 	// fi stores the location of the error
 	fi FuncInfo
 
@@ -44,12 +82,161 @@ type signatured struct {
 	// the erroring function. The StackString function will add this between
 	// parenthesis after the function name.
 	argStringer interface{ String() string }
+
+	// pcs is the stack fingerprint recorded if DetectGaps is on.
+	pcs []uintptr
+
+	// fields holds the key/value pairs given to FieldBuilder.With.
+	fields []Field
 }
 
 func (s *signatured) Error() string {
 	return s.message
 }
 
+// Unwrap returns the operand of the %w verb in the message format, if any, or
+// a Group of the operands if there are several.
+func (s *signatured) Unwrap() error {
+	return s.cause
+}
+
+// Fields returns the key/value pairs given to FieldBuilder.With.
+func (s *signatured) Fields() []Field {
+	return s.fields
+}
+
+// fingerprint returns the call stack recorded if DetectGaps was on.
+func (s *signatured) fingerprint() []uintptr {
+	return s.pcs
+}
+
+// stackMessage returns the message as StackString shows it.
+func (s *signatured) stackMessage() string {
+	return s.short
+}
+
+// errorf sets the message and cause of s the way fmt.Errorf does. The
+// operands of %w verbs become the cause, as a Group if there are several. If
+// elide is set, their text is left out of the message that StackString shows,
+// since StackString shows the cause as the next layer.
+func (s *signatured) errorf(elide bool, msg string, args ...interface{}) {
+	format := []byte(msg)
+	elided := make([]interface{}, len(args))
+	copy(elided, args)
+	var causes Group
+	for _, verb := range wrapVerbs(msg) {
+		if verb.arg >= len(args) {
+			continue
+		}
+		cause, ok := args[verb.arg].(error)
+		if !ok || cause == nil {
+			continue
+		}
+		// %w formats like %v. Rewriting it lets fmt.Sprintf handle several
+		// of them, and Go versions whose fmt.Errorf does not know %w.
+		format[verb.pos] = 'v'
+		elided[verb.arg] = elidedError{}
+		causes = append(causes, cause)
+	}
+	s.message = fmt.Sprintf(string(format), args...)
+	s.short = s.message
+	switch len(causes) {
+	case 0:
+		return
+	case 1:
+		s.cause = causes[0]
+	default:
+		s.cause = causes
+	}
+	if !elide {
+		return
+	}
+	s.short = strings.NewReplacer(
+		": "+elidedMarker, "",
+		elidedMarker+": ", "",
+		elidedMarker, "",
+	).Replace(fmt.Sprintf(string(format), elided...))
+}
+
+// wrapVerb is a %w verb in a format string.
+type wrapVerb struct {
+	pos int // the index of the 'w' in the format string
+	arg int // the index of the operand in the args
+}
+
+// wrapVerbs finds the %w verbs in format, and the args that they format,
+// following the rules of the fmt package for explicit argument indexes and
+// for '*' widths and precisions.
+func wrapVerbs(format string) []wrapVerb {
+	var verbs []wrapVerb
+	arg := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format); i++ {
+			c := format[i]
+			switch {
+			case strings.IndexByte("+-# 0.", c) >= 0 || '1' <= c && c <= '9':
+				continue
+			case c == '*':
+				arg++
+				continue
+			case c == '[':
+				end := strings.IndexByte(format[i:], ']')
+				if end < 0 {
+					return verbs
+				}
+				if n, err := strconv.Atoi(format[i+1 : i+end]); err == nil {
+					arg = n - 1
+				}
+				i += end
+				continue
+			case c == 'w':
+				verbs = append(verbs, wrapVerb{pos: i, arg: arg})
+			}
+			if c != '%' {
+				arg++
+			}
+			break
+		}
+	}
+	return verbs
+}
+
+// withCause returns s, or if s has a cause, s with a Cause() method.
+func (s *signatured) withCause() error {
+	if s.cause == nil {
+		return s
+	}
+	return signaturedCause{s}
+}
+
+// signaturedCause is a signatured error made with a %w verb. Unlike errors
+// without a cause, it has a Cause() method like the errors of
+// github.com/pkg/errors, whose Cause function stops at errors without one.
+type signaturedCause struct {
+	*signatured
+}
+
+// Cause returns the operand of the %w verb in the message format.
+func (sc signaturedCause) Cause() error {
+	return sc.cause
+}
+
+// elidedMarker is the text of an elidedError. errorf removes it from the
+// message, along with the ": " separating it from the rest.
+const elidedMarker = "\x00elided\x00"
+
+// elidedError stands in for a %w operand.
+type elidedError struct{}
+
+func (elidedError) Error() string { return elidedMarker }
+
+func (elidedError) Format(s fmt.State, verb rune) {
+	io.WriteString(s, elidedMarker)
+}
+
 // FuncInfo returns the location of the error.
 func (s *signatured) FuncInfo() FuncInfo {
 	return s.fi
@@ -62,6 +249,29 @@ func (s *signatured) ArgStringer() interface{ String() string } {
 	return s.argStringer
 }
 
+// Format implements fmt.Formatter. %v and %s print the message, %q quotes it,
+// %+v prints the whole chain like StackString, and %#v prints a Go-syntax
+// representation including the FuncInfo.
+func (s *signatured) Format(st fmt.State, verb rune) {
+	formatError(st, verb, s)
+}
+
+// GoString returns a Go-syntax representation of the error, including its
+// location and args.
+func (s *signatured) GoString() string {
+	var args string
+	if s.argStringer != nil {
+		args = DefaultScrubber.Scrub(s.argStringer.String())
+	}
+	return fmt.Sprintf(
+		"&errors.signatured{message:%q, fi:%s, argStringer:%q, cause:%s}",
+		DefaultScrubber.Scrub(s.message),
+		goStringFuncInfo(s.fi),
+		args,
+		goStringOf(s.cause),
+	)
+}
+
 // stringStringer has a String method that returns the underlying string value.
 type stringStringer string
 
@@ -80,47 +290,67 @@ func (fs formatStringer) String() string {
 	return fmt.Sprintf(fs.fmt, fs.params...)
 }
 
-// argsBuilder is the underlying type for NewBuilder.
+// argsBuilder is the underlying type for NewBuilder and NewArgsBuilder.
 type argsBuilder struct {
-	argString string
+	argStringer interface{ String() string }
+	fields      []Field
 }
 
 // NewBuilder returns an error builder that attaches info about the function
 // where the error happened, and the args with which the function was called.
-func NewBuilder(argFmt string, args ...interface{}) Builder {
+func NewBuilder(argFmt string, args ...interface{}) FieldBuilder {
 	ab := new(argsBuilder)
-	ab.argString = fmt.Sprintf(argFmt, args...)
+	ab.argStringer = stringStringer(fmt.Sprintf(argFmt, args...))
 	return ab
 }
 
+// With returns a copy of the builder whose errors also have the given
+// alternating keys and values, as reported by Fields.
+func (ab *argsBuilder) With(keyvals ...interface{}) FieldBuilder {
+	clone := *ab
+	clone.fields = appendFields(ab.fields, keyvals)
+	return &clone
+}
+
 // Errorf is the same as fmt.Errorf, except that the error message gets
 // FuncInfo() and ArgStringer() methods, describing the context of the error.
+// If msg has a %w verb, the error unwraps to its operand, which StackString
+// shows as the next layer. Several %w verbs make it unwrap to a Group of their
+// operands. Unlike fmt.Errorf, this works with every Go version.
 func (ab *argsBuilder) Errorf(msg string, args ...interface{}) error {
-	return &signatured{
-		message:     fmt.Sprintf(msg, args...),
-		fi:          NewFuncInfo(1),
-		argStringer: stringStringer(ab.argString),
+	s := &signatured{
+		fi:          NewLazyFuncInfo(1),
+		pcs:         fingerprint(),
+		argStringer: ab.argStringer,
+		fields:      ab.fields,
 	}
+	s.errorf(true, msg, args...)
+	return s.withCause()
 }
 
 // Wrap replaces errors.Wrap, except that the error additionally implements the
 // Wrapper() method, whose return value implements the FuncInfo() and
-// ArgStringer() methods to describe the context of the error.
+// ArgStringer() methods to describe the context of the error. If msg has a %w
+// verb, the Wrapper() unwraps to its operand.
 func (ab *argsBuilder) Wrap(
 	err error,
 	msg string,
 	args ...interface{},
 ) Wrapped {
-	return WrapWith(err, &signatured{
-		message:     fmt.Sprintf(msg, args...),
-		fi:          NewFuncInfo(1),
-		argStringer: stringStringer(ab.argString),
-	})
+	s := &signatured{
+		fi:          NewLazyFuncInfo(1),
+		pcs:         fingerprint(),
+		argStringer: ab.argStringer,
+		fields:      ab.fields,
+	}
+	s.errorf(false, msg, args...)
+	return WrapWith(err, s)
 }
 
 type lazyArgsBuilder struct {
 	argFmt string
 	args   []interface{}
+	fields []Field
 }
 
 // NewLazyBuilder SHOULD NOT be used unless it is known that NewBuilder
@@ -132,15 +362,27 @@ type lazyArgsBuilder struct {
 // per second, but misleading debug messages can result if the arguments have
 // changed since the function was first called. As a debug warning, any args
 // are labeled "<lazy>" by the ArgStringer().
-func NewLazyBuilder(argFmt string, args ...interface{}) Builder {
+func NewLazyBuilder(argFmt string, args ...interface{}) FieldBuilder {
 	lab := new(lazyArgsBuilder)
 	lab.argFmt = argFmt
 	lab.args = args
 	return lab
 }
 
+// With returns a copy of the builder whose errors also have the given
+// alternating keys and values, as reported by Fields. The values are kept as
+// they are, like the args.
+func (lab *lazyArgsBuilder) With(keyvals ...interface{}) FieldBuilder {
+	clone := *lab
+	clone.fields = appendFields(lab.fields, keyvals)
+	return &clone
+}
+
 // Errorf is the same as fmt.Errorf, except that the error message gets
 // FuncInfo() and ArgStringer() methods, describing the context of the error.
+// If msg has a %w verb, the error unwraps to its operand, which StackString
+// shows as the next layer. Several %w verbs make it unwrap to a Group of their
+// operands. Unlike fmt.Errorf, this works with every Go version.
 // On lazy builders, ArgStringer() does its formatting computations when its
 // String() method gets called.
 func (lab *lazyArgsBuilder) Errorf(msg string, args ...interface{}) error {
@@ -148,16 +390,20 @@ func (lab *lazyArgsBuilder) Errorf(msg string, args ...interface{}) error {
 	if len(argFmt) != 0 { // if no args, nothing to warn about
 		argFmt = "<lazy> " + argFmt
 	}
-	return &signatured{
-		message:     fmt.Sprintf(msg, args...),
-		fi:          NewFuncInfo(1),
+	s := &signatured{
+		fi:          NewLazyFuncInfo(1),
+		pcs:         fingerprint(),
 		argStringer: formatStringer{argFmt, lab.args},
+		fields:      lab.fields,
 	}
+	s.errorf(true, msg, args...)
+	return s.withCause()
 }
 
 // Wrap replaces errors.Wrap, except that the error additionally implements the
 // Wrapper() method, whose return value implements the FuncInfo() and
-// ArgStringer() methods to describe the context of the error.
+// ArgStringer() methods to describe the context of the error. If msg has a %w
+// verb, the Wrapper() unwraps to its operand.
 // On lazy builders, ArgStringer() does its formatting computations when its
 // String() method gets called.
 func (lab *lazyArgsBuilder) Wrap(
@@ -169,9 +415,12 @@ func (lab *lazyArgsBuilder) Wrap(
 	if len(argFmt) != 0 { // if no args, nothing to warn about
 		argFmt = "<lazy> " + argFmt
 	}
-	return WrapWith(err, &signatured{
-		message:     fmt.Sprintf(msg, args...),
-		fi:          NewFuncInfo(1),
+	s := &signatured{
+		fi:          NewLazyFuncInfo(1),
+		pcs:         fingerprint(),
 		argStringer: formatStringer{argFmt, lab.args},
-	})
+		fields:      lab.fields,
+	}
+	s.errorf(false, msg, args...)
+	return WrapWith(err, s)
 }
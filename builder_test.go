@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,7 @@ func TestBuiltinBuilder(t *testing.T) {
 }
 
 func TestNewBuilder(t *testing.T) {
+	defer OverrideMainModule("")()
 	changingMap := map[string]string{"first": "orig"}
 	b := NewBuilder("t, %q", changingMap)
 	changingMap["second"] = "new"
@@ -26,6 +28,7 @@ func TestNewBuilder(t *testing.T) {
 }
 
 func TestNewLazyBuilder(t *testing.T) {
+	defer OverrideMainModule("")()
 	changingMap := map[string]string{"first": "orig"}
 	b := NewLazyBuilder("t, %q", changingMap)
 	changingMap["second"] = "new"
@@ -37,3 +40,127 @@ func TestNewLazyBuilder(t *testing.T) {
 		StackString(err),
 	)
 }
+
+func TestSignaturedFormat(t *testing.T) {
+	b := NewBuilder("%q", "x")
+	err := b.Wrap(New("cause"), "b")
+	assert.Equal(t, "b", fmt.Sprintf("%v", err))
+	assert.Equal(t, `"b"`, fmt.Sprintf("%q", err))
+	assert.Regexp(
+		t,
+		`^[^ ]+\.TestSignaturedFormat\("x"\) builder_test\.go:[0-9]+ b\ncause$`,
+		fmt.Sprintf("%+v", err),
+	)
+
+	sig := err.(Wrapped).(interface{ Wrapper() error }).Wrapper()
+	assert.Equal(t, "b", fmt.Sprintf("%s", sig))
+	assert.Regexp(
+		t,
+		`^[^ ]+\.TestSignaturedFormat\("x"\) builder_test\.go:[0-9]+ b$`,
+		fmt.Sprintf("%+v", sig),
+	)
+	assert.Regexp(
+		t,
+		`^&errors\.signatured\{message:"b", fi:errors\.FuncInfo\{FuncName:"[^"]+\.TestSignaturedFormat", File:"[^"]+/builder_test\.go", Line:[0-9]+\}, argStringer:"\\"x\\"", cause:<nil>\}$`,
+		fmt.Sprintf("%#v", sig),
+	)
+}
+
+func TestBuilderErrorfWrapVerb(t *testing.T) {
+	cause := New("no such file")
+	for _, b := range []Builder{NewBuilder("%q", "x"), NewLazyBuilder("")} {
+		err := b.Errorf("reading %s: %w", "cfg", cause)
+		assert.Equal(t, "reading cfg: no such file", err.Error())
+		assert.Equal(t, cause, Unwrap(err))
+		assert.True(t, Is(err, cause))
+		assert.Implements(t, (*interface{ FuncInfo() FuncInfo })(nil), err)
+		assert.Regexp(
+			t,
+			`^[^ ]+\.TestBuilderErrorfWrapVerb\(.*\) builder_test\.go:[0-9]+ reading cfg\nno such file$`,
+			StackString(err),
+		)
+
+		err = b.Errorf("%w: while reading", cause)
+		assert.Regexp(t, ` while reading\nno such file$`, StackString(err))
+
+		err = b.Errorf("%w: expected one of:", cause)
+		assert.Regexp(t, `[0-9] expected one of:\nno such file$`, StackString(err))
+
+		err = b.Errorf("bad value %q:: %w", "v", cause)
+		assert.Equal(t, `bad value "v":: no such file`, err.Error())
+		assert.Regexp(t, `[0-9] bad value "v":\nno such file$`, StackString(err))
+
+		err = b.Errorf("open: %w: retrying", cause)
+		assert.Regexp(t, `[0-9] open: retrying\nno such file$`, StackString(err))
+
+		err = b.Errorf("%v: %w", cause, cause)
+		assert.Equal(t, "no such file: no such file", err.Error())
+		assert.Regexp(t, `[0-9] no such file\nno such file$`, StackString(err))
+
+		other := New("timed out")
+		err = b.Errorf("%w, then %[1]w and %w", cause, other)
+		assert.Equal(t, "no such file, then no such file and timed out", err.Error())
+		assert.Equal(t, Group{cause, cause, other}, Unwrap(err))
+		assert.True(t, Is(err, cause))
+		assert.True(t, Is(err, other))
+
+		err = b.Errorf("%w", "not an error")
+		assert.Equal(t, "%!w(string=not an error)", err.Error())
+		assert.Nil(t, Unwrap(err))
+
+		err = b.Errorf("reading %s", "cfg")
+		assert.Nil(t, Unwrap(err))
+		assert.Regexp(t, ` reading cfg$`, StackString(err))
+	}
+}
+
+func TestWrapVerbs(t *testing.T) {
+	cases := []struct {
+		format string
+		want   []wrapVerb
+	}{
+		{"no verbs", nil},
+		{"%w", []wrapVerb{{1, 0}}},
+		{"%% %s: %w", []wrapVerb{{8, 1}}},
+		{"%*d %-5.*w", []wrapVerb{{9, 3}}},
+		{"%[2]w %[1]v %w", []wrapVerb{{4, 1}, {13, 1}}},
+		{"%+w%#v%w", []wrapVerb{{2, 0}, {7, 2}}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, wrapVerbs(c.format), c.format)
+	}
+}
+
+func TestBuilderWrapWrapVerb(t *testing.T) {
+	cause := New("no such file")
+	other := New("retry limit")
+	err := NewBuilder("").Wrap(cause, "giving up: %w", other)
+	assert.Equal(t, "giving up: retry limit", err.Error())
+	assert.Equal(t, cause, Unwrap(err))
+	assert.True(t, Is(err, cause))
+	assert.True(t, Is(err, other))
+	assert.Regexp(
+		t,
+		`\) builder_test\.go:[0-9]+ giving up: retry limit\nno such file$`,
+		StackString(err),
+	)
+}
+
+var benchErr error
+
+func BenchmarkBuilderErrorf(b *testing.B) {
+	b.ReportAllocs()
+	eb := NewBuilder("%d", 1)
+	for i := 0; i < b.N; i++ {
+		benchErr = eb.Errorf("failed")
+	}
+}
+
+func BenchmarkBuilderErrorfStackString(b *testing.B) {
+	b.ReportAllocs()
+	eb := NewBuilder("%d", 1)
+	for i := 0; i < b.N; i++ {
+		benchErr = eb.Errorf("failed")
+		StackString(benchErr)
+	}
+}
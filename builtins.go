@@ -18,7 +18,8 @@ var (
 // target to that error value and returns true.
 //
 // The chain consists of err itself followed by the sequence of errors obtained
-// by repeatedly calling Unwrap.
+// by repeatedly calling Unwrap. If Unwrap returns a []error, each of them is
+// searched in turn, depth first.
 //
 // An error matches target if the error's concrete value is assignable to the
 // value pointed to by target, or if the error has a method As(interface{})
@@ -39,49 +40,26 @@ func As(err error, target interface{}) bool {
 	if e := typ.Elem(); e.Kind() != reflect.Interface && !e.Implements(errorType) {
 		panic("errors: *target must be interface or implement error")
 	}
-	targetType := typ.Elem()
-	for err != nil {
-		if reflect.TypeOf(err).AssignableTo(targetType) {
-			val.Elem().Set(reflect.ValueOf(err))
-			return true
-		}
-		if x, ok := err.(interface{ As(interface{}) bool }); ok && x.As(target) {
-			return true
-		}
-		err = Unwrap(err)
-	}
-	return false
+	return as(err, target, val, typ.Elem())
 }
 
-var errorType = reflect.TypeOf((*error)(nil)).Elem()
-
 // Is reports whether any error in err's chain matches target.
 //
 // The chain consists of err itself followed by the sequence of errors obtained
-// by repeatedly calling Unwrap.
+// by repeatedly calling Unwrap. If Unwrap returns a []error, each of them is
+// searched in turn, depth first.
 //
 // An error is considered to match a target if it is equal to that target or if
 // it implements a method Is(error) bool such that Is(target) returns true.
+// Errors whose dynamic values cannot be compared are not equal, rather than
+// causing a panic.
 func Is(err, target error) bool {
 	if target == nil {
 		return err == target
 	}
 
 	isComparable := reflect.TypeOf(target).Comparable()
-	for {
-		if isComparable && err == target {
-			return true
-		}
-		if x, ok := err.(interface{ Is(error) bool }); ok && x.Is(target) {
-			return true
-		}
-		// TODO: consider supporing target.Is(err). This would allow
-		// user-definable predicates, but also may allow for coping with sloppy
-		// APIs, thereby making it easier to get away with them.
-		if err = Unwrap(err); err == nil {
-			return false
-		}
-	}
+	return is(err, target, isComparable)
 }
 
 // Unwrap returns the result of calling the Unwrap method on err, if err's
@@ -0,0 +1,23 @@
+// +build go1.20 !go1.13
+
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Is and As only descend into `Unwrap() []error` on Go 1.20 and later, and in
+// the backported implementations.
+
+func TestIsAsMultiUnwrap(t *testing.T) {
+	errTimeout := New("timeout")
+	te := new(testError)
+	multi := &multiError{"multi", []error{New("a"), Wrap(errTimeout, "b"), te}}
+	assert.True(t, Is(Wrap(multi, "outer"), errTimeout))
+	assert.False(t, Is(multi, New("timeout")))
+	var target *testError
+	assert.True(t, As(multi, &target))
+	assert.Equal(t, te, target)
+}
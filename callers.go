@@ -0,0 +1,159 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// maxCallers limits how many stack frames WithStack records.
+const maxCallers = 64
+
+// withStack is an error annotated with the call stack where it was created.
+type withStack struct {
+	error
+	pcs []uintptr
+}
+
+// WithStack returns an error that behaves like err, but that also records the
+// call stack of its caller. Only the program counters are stored; they are
+// resolved to functions and lines when the error is printed. StackString
+// prints the recorded frames below the rest of the chain, leaving out the
+// functions that already appear as layers made by a Builder. If err is nil,
+// WithStack returns nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &withStack{error: err, pcs: callers(1)}
+}
+
+// callers returns the program counters of the call stack, like
+// runtime.Callers. A skip of 0 refers to the caller of callers.
+func callers(skip int) []uintptr {
+	var pcs [maxCallers]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	return append([]uintptr(nil), pcs[:n]...)
+}
+
+// Unwrap returns the annotated error.
+func (ws *withStack) Unwrap() error {
+	return ws.error
+}
+
+// Cause returns the annotated error, like the errors of github.com/pkg/errors.
+func (ws *withStack) Cause() error {
+	return ws.error
+}
+
+// Callers returns the program counters of the stack frames recorded by
+// WithStack, innermost first, suitable for runtime.CallersFrames.
+func (ws *withStack) Callers() []uintptr {
+	return ws.pcs
+}
+
+// Format implements fmt.Formatter like the other errors of this package.
+func (ws *withStack) Format(s fmt.State, verb rune) {
+	formatError(s, verb, ws)
+}
+
+// uncoveredFrames returns the frames of pcs, leaving out the functions which
+// already have a located layer in stack.
+func uncoveredFrames(pcs []uintptr, stack []error) []callerFrame {
+	shown := make(map[string]bool)
+	for _, err := range stack {
+		if fi := funcInfoOf(err); fi != nil {
+			shown[fi.FuncName()] = true
+		}
+	}
+	var frames []callerFrame
+	for _, frame := range callersFrames(pcs) {
+		if !shown[frame.Function] {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// detectGaps is 1 if builders should record stack fingerprints.
+var detectGaps int32
+
+// DetectGaps turns on or off the recording of a stack fingerprint on every
+// error made by a Builder. When two adjacent located layers both have
+// fingerprints, StackString reports the functions between them that did not
+// add a layer, like this:
+//
+//	... (2 frames not wrapped: ~/pkg.A, ~/pkg.B)
+//
+// This helps find where NewBuilder is missing in a codebase. Recording costs a
+// runtime.Callers call per error, so it is off by default.
+func DetectGaps(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&detectGaps, v)
+}
+
+// fingerprint returns the call stack of its caller's caller if DetectGaps is
+// on, otherwise nil.
+func fingerprint() []uintptr {
+	if atomic.LoadInt32(&detectGaps) == 0 {
+		return nil
+	}
+	return callers(2)
+}
+
+// fingerprintOf returns the stack fingerprint of err or of its Wrapper(), or
+// nil if neither has one.
+func fingerprintOf(err error) []uintptr {
+	switch err := err.(type) {
+	case interface{ fingerprint() []uintptr }:
+		return err.fingerprint()
+	case interface{ Wrapper() error }:
+		return fingerprintOf(err.Wrapper())
+	}
+	return nil
+}
+
+// gapFrames returns the frames between the located layers with the
+// fingerprints outer and inner, or nil if there are none or if they cannot be
+// told.
+func gapFrames(outer, inner []uintptr) []callerFrame {
+	if len(outer) == 0 || len(inner) == 0 ||
+		len(outer) == maxCallers || len(inner) == maxCallers {
+		return nil
+	}
+	outerFrames := callersFrames(outer)
+	innerFrames := callersFrames(inner)
+	j := len(innerFrames) - len(outerFrames)
+	if j < 1 || innerFrames[j].Function != outerFrames[0].Function {
+		return nil
+	}
+	for i, frame := range outerFrames[1:] {
+		if innerFrames[j+1+i] != frame {
+			return nil
+		}
+	}
+	return innerFrames[1:j]
+}
+
+// callerFrame identifies a frame by function and line.
+type callerFrame struct {
+	Function, File string
+	Line           int
+}
+
+// callersFrames resolves pcs to their frames, including inlined ones.
+func callersFrames(pcs []uintptr) []callerFrame {
+	var result []callerFrame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		result = append(result, callerFrame{frame.Function, frame.File, frame.Line})
+		if !more {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withStackOrigin() error {
+	return WithStack(New("origin"))
+}
+
+func withStackMiddle() error {
+	return withStackOrigin()
+}
+
+func TestWithStack(t *testing.T) {
+	assert.Nil(t, WithStack(nil))
+
+	err := withStackMiddle()
+	assert.Equal(t, "origin", err.Error())
+	assert.Equal(t, "origin", Unwrap(err).Error())
+	assert.NotEmpty(t, err.(interface{ Callers() []uintptr }).Callers())
+
+	lines := strings.Split(StackString(err), "\n")
+	require.True(t, len(lines) >= 4, lines)
+	assert.Equal(t, "origin", lines[0])
+	assert.Regexp(t, `^\t[^ ]+\.withStackOrigin callers_test\.go:[0-9]+$`, lines[1])
+	assert.Regexp(t, `^\t[^ ]+\.withStackMiddle callers_test\.go:[0-9]+$`, lines[2])
+	assert.Regexp(t, `^\t[^ ]+\.TestWithStack callers_test\.go:[0-9]+$`, lines[3])
+	assert.Equal(t, StackString(err), fmt.Sprintf("%+v", err))
+}
+
+func TestWithStackCollapsesLayers(t *testing.T) {
+	b := NewBuilder("")
+	err := b.Wrap(withStackMiddle(), "outer")
+	lines := strings.Split(StackString(err), "\n")
+	require.True(t, len(lines) >= 4, lines)
+	assert.Regexp(t, `\.TestWithStackCollapsesLayers\(\) callers_test\.go:[0-9]+ outer$`, lines[0])
+	assert.Equal(t, "origin", lines[1])
+	assert.Regexp(t, `\.withStackOrigin `, lines[2])
+	assert.Regexp(t, `\.withStackMiddle `, lines[3])
+	for _, line := range lines[4:] {
+		assert.NotContains(t, line, "TestWithStackCollapsesLayers")
+	}
+}
+
+func gapOuter() error {
+	b := NewBuilder("")
+	return b.Wrap(gapMiddle(), "outer")
+}
+
+func gapMiddle() error {
+	return gapInner()
+}
+
+func gapInner() error {
+	b := NewBuilder("")
+	return b.Errorf("inner")
+}
+
+func gapAdjacentOuter() error {
+	b := NewBuilder("")
+	return b.Wrap(gapInner(), "outer")
+}
+
+func TestDetectGaps(t *testing.T) {
+	lines := strings.Split(StackString(gapOuter()), "\n")
+	assert.Len(t, lines, 2, "off by default")
+
+	DetectGaps(true)
+	defer DetectGaps(false)
+
+	lines = strings.Split(StackString(gapOuter()), "\n")
+	require.Len(t, lines, 3)
+	assert.Regexp(t, `\.gapOuter\(\) callers_test\.go:[0-9]+ outer$`, lines[0])
+	assert.Regexp(t, `^\.\.\. \(1 frame not wrapped: [^ ]+\.gapMiddle\)$`, lines[1])
+	assert.Regexp(t, `\.gapInner\(\) callers_test\.go:[0-9]+ inner$`, lines[2])
+
+	lines = strings.Split(StackString(gapAdjacentOuter()), "\n")
+	assert.Len(t, lines, 2, "no gap between direct caller and callee")
+}
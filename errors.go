@@ -29,7 +29,8 @@ main.main() main.go:12 parse failed
 */
 import (
 	"fmt"
-	"path"
+	"io"
+	"reflect"
 	"strings"
 )
 
@@ -52,48 +53,124 @@ func Wrap(cause error, msg string, vars ...interface{}) Wrapped {
 
 // WrapWith takes two errors and wraps the first provided error with the
 // second. This is particularly useful when the wrapping error itself is a
-// special type with custom fields and methods.
+// special type with custom fields and methods. Each call returns a new
+// pointer, so two wraps of the same errors are not ==, but Is matches them.
 func WrapWith(cause, err error) Wrapped {
-	return wrapped{error: err, wrapped: cause}
+	return &wrapped{error: err, wrapped: cause}
 }
 
 // Unwrap returns the cause of the sender.
-func (w wrapped) Unwrap() error {
+func (w *wrapped) Unwrap() error {
+	return w.wrapped
+}
+
+// Cause returns the cause of the sender, like the errors of
+// github.com/pkg/errors. If the cause is nil, it returns the Wrapper(), so that
+// loops like pkg/errors.Cause do not end with nil.
+func (w *wrapped) Cause() error {
+	if w.wrapped == nil {
+		return w.error
+	}
 	return w.wrapped
 }
 
 // Wrapper returns the error value without the error it wraps. This allows
 // access to custom fields of the error, without interference from the Unwrap
 // chain like in Is or As.
-func (w wrapped) Wrapper() error {
+func (w *wrapped) Wrapper() error {
 	return w.error
 }
 
 // Is returns whether the wrapping error or a member of its cause chain matches
 // the target.
-func (w wrapped) Is(target error) bool {
-	switch target := target.(type) {
-	case wrapped:
-		if w == target {
-			return true
-		}
+func (w *wrapped) Is(target error) bool {
+	if equal(w, target) {
+		return true
 	}
 	return Is(w.Wrapper(), target) || Is(w.Unwrap(), target)
 }
 
 // As assigns the first compatible error it finds in the cause chain to the
 // target, and returns true if successful. If successful, Is(w, target) will be
-// true.
-func (w wrapped) As(target interface{}) bool {
-	switch target := target.(type) {
-	case wrapped:
-		target.error = w.error
-		target.wrapped = w.wrapped
-		return true
-	}
+// true. The Wrapper() is searched before the cause, so that As with a Located
+// target finds the outermost located layer.
+func (w *wrapped) As(target interface{}) bool {
 	return As(w.Wrapper(), target) || As(w.Unwrap(), target)
 }
 
+// Format implements fmt.Formatter. %v and %s print the message, %q quotes it,
+// %+v prints the whole chain like StackString, and %#v prints a Go-syntax
+// representation.
+func (w *wrapped) Format(s fmt.State, verb rune) {
+	formatError(s, verb, w)
+}
+
+// GoString returns a Go-syntax representation of the error and its cause.
+func (w *wrapped) GoString() string {
+	return fmt.Sprintf(
+		"&errors.wrapped{error:%s, wrapped:%s}",
+		goStringOf(w.error),
+		goStringOf(w.wrapped),
+	)
+}
+
+// formatError implements fmt.Formatter for the error types of this package.
+func formatError(s fmt.State, verb rune, err error) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, StackString(err))
+	case verb == 'v' && s.Flag('#'):
+		if gs, ok := err.(fmt.GoStringer); ok {
+			io.WriteString(s, gs.GoString())
+			return
+		}
+		fmt.Fprintf(s, "%#v", DefaultScrubber.Scrub(err.Error()))
+	case verb == 'v' || verb == 's':
+		fmt.Fprintf(s, directive(s, 's'), err.Error())
+	case verb == 'q':
+		fmt.Fprintf(s, directive(s, 'q'), err.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(%T=%s)", verb, err, err.Error())
+	}
+}
+
+// goStringOf returns the %#v of an error nested in one of this package, without
+// the text that DefaultScrubber removes. The errors of this package scrub their
+// own representation. Other errors are shown as their type and scrubbed message
+// if their representation has anything to scrub.
+func goStringOf(err error) string {
+	switch err.(type) {
+	case nil, *wrapped, *signatured, signaturedCause, *withFields,
+		withFieldsCause, *withStack:
+		return fmt.Sprintf("%#v", err)
+	}
+	goString := fmt.Sprintf("%#v", err)
+	if DefaultScrubber.Scrub(goString) == goString {
+		return goString
+	}
+	return fmt.Sprintf("%T(%q)", err, DefaultScrubber.Scrub(err.Error()))
+}
+
+// directive rebuilds the formatting directive described by s, substituting
+// verb, so that flags, width and precision are honored like the stdlib does.
+func directive(s fmt.State, verb rune) string {
+	var b strings.Builder
+	b.WriteByte('%')
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			b.WriteRune(flag)
+		}
+	}
+	if width, ok := s.Width(); ok {
+		fmt.Fprintf(&b, "%d", width)
+	}
+	if prec, ok := s.Precision(); ok {
+		fmt.Fprintf(&b, ".%d", prec)
+	}
+	b.WriteRune(verb)
+	return b.String()
+}
+
 // Stack returns a slice of all the errors found by recursively calling
 // Unwrap() on the provided error. Errors causing other errors appear later.
 func Stack(err error) []error {
@@ -110,57 +187,23 @@ func Stack(err error) []error {
 }
 
 // StackString recursively calls Unwrap() on the given error and stringifies
-// all the errors in the chain.
-//
-// The stringification adds location prefixes to errors that additionally
-// implement `FuncInfo() FuncInfo` and optionally `ArgStringer() interface{
-// String() string }`.
-//
-// The stringification can be overridden if the error implements `StackString()
-// string`.
+// all the errors in the chain, using DefaultFormatter. See
+// Formatter.StackString.
 func StackString(err error) string {
-	stack := Stack(err)
-	messages := make([]string, 0, len(stack))
-	for _, err := range stack {
-		messages = append(messages, StackStringAt(err))
-	}
-	return strings.Join(messages, "\n")
+	return DefaultFormatter.StackString(err)
 }
 
 // StackStringAt returns one level of StackString.
 func StackStringAt(err error) string {
-	switch err := err.(type) {
-	case interface{ StackString() string }:
-		return err.StackString()
-	case interface {
-		error
-		FuncInfo() FuncInfo
-		ArgStringer() interface{ String() string }
-	}:
-		return fmt.Sprintf(
-			"%s(%s) %s:%d %v",
-			RelativeModule(err.FuncInfo().FuncName(), MainModule()),
-			err.ArgStringer().String(),
-			path.Base(err.FuncInfo().File()),
-			err.FuncInfo().Line(),
-			err,
-		)
-	case interface {
-		error
-		FuncInfo() FuncInfo
-	}:
-		return fmt.Sprintf(
-			"%s %s:%d %v",
-			RelativeModule(err.FuncInfo().FuncName(), MainModule()),
-			path.Base(err.FuncInfo().File()),
-			err.FuncInfo().Line(),
-			err,
-		)
-	case interface {
-		error
-		Wrapper() error
-	}:
-		return StackStringAt(err.Wrapper())
+	return DefaultFormatter.StackStringAt(err)
+}
+
+// layerMessage returns the message of err as StackStringAt shows it. This is
+// the same as Error(), unless the error shows its cause as the next layer and
+// leaves the cause's text out of its own message.
+func layerMessage(err error) string {
+	if sm, ok := err.(interface{ stackMessage() string }); ok {
+		return sm.stackMessage()
 	}
 	return err.Error()
 }
@@ -170,22 +213,17 @@ func StackStringAt(err error) string {
 // fetch might fail only if all the mirrors fail to respond.
 type Group []error
 
-// StackString formats a []error as a recursive list of StackString-ed errors
-// if len() is 2 or more, otherwise as a single StackString-ed error if len()
-// is 1, otherwise as nothing if len() is 0.
+// StackString formats the members of a []error as the branches of a tree of
+// StackString-ed errors.
 func (g Group) StackString() string {
-	l := []error(g)
-	switch len(l) {
-	case 0:
-		return ""
-	case 1:
-		return StackString(l[0])
-	}
-	messages := make([]string, 0, len(l))
-	for _, err := range l {
-		messages = append(messages, StackString(err))
-	}
-	return fmt.Sprintf("[\n%s\n]", indent(strings.Join(messages, "\n,\n")))
+	return DefaultFormatter.StackStringAt(g)
+}
+
+// Unwrap returns the members of the group. This lets Is and As from Go 1.20
+// and later descend into every member. Earlier versions rely on the Is and As
+// methods of Group instead.
+func (g Group) Unwrap() []error {
+	return g
 }
 
 // Error formats a []error as a list of errors if len() is 2 or more, otherwise
@@ -202,9 +240,63 @@ func (g Group) Error() string {
 	for _, err := range l {
 		messages = append(messages, err.Error())
 	}
-	return fmt.Sprintf("[\n%s\n]", indent(strings.Join(messages, "\n,\n")))
+	return fmt.Sprintf("[\n%s\n]", indent(strings.Join(messages, "\n,\n"), "\n", "\t"))
+}
+
+// equal reports whether a == b, treating values whose dynamic types cannot be
+// compared as unequal instead of panicking. Wrapped errors are equal if they
+// wrap equal errors with equal errors, and Groups are equal if their members
+// are.
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case *wrapped:
+		if b, ok := b.(*wrapped); ok && a != nil && b != nil {
+			return a == b || equal(a.error, b.error) && equal(a.wrapped, b.wrapped)
+		}
+	case Group:
+		b, ok := b.(Group)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if a == nil || b == nil {
+		return a == b
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Type() == vb.Type() && canCompare(va) && canCompare(vb) && a == b
+}
+
+// canCompare returns whether == can compare v without panicking. Unlike
+// reflect.Type.Comparable, it looks into the values held by interfaces.
+func canCompare(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		return v.IsNil() || canCompare(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !canCompare(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !canCompare(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.Type().Comparable()
 }
 
-func indent(s string) string {
-	return "\t" + strings.ReplaceAll(s, "\n", "\n\t")
+// indent prefixes each line of s, as delimited by sep, with prefix.
+func indent(s, sep, prefix string) string {
+	return prefix + strings.Replace(s, sep, sep+prefix, -1)
 }
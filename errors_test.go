@@ -40,8 +40,8 @@ func TestGroup(t *testing.T) {
 
 	bc := Wrap(fmt.Errorf("c"), "b")
 	g := Group([]error{a, bc})
-	assert.Equal(t, "[\n\ta\n\t,\n\tb\n\tc\n]", g.StackString())
-	assert.Equal(t, "[\n\ta\n\t,\n\tb\n\tc\n]", StackString(g))
+	assert.Equal(t, "├─ a\n└─ b\n   c", g.StackString())
+	assert.Equal(t, "├─ a\n└─ b\n   c", StackString(g))
 	assert.Equal(t, "[\n\ta\n\t,\n\tb\n]", g.Error())
 }
 
@@ -88,3 +88,122 @@ func TestWrapWithIs(t *testing.T) {
 	assert.False(t, Is(tokenErr, a2))
 	assert.False(t, Is(tokenErr, a3))
 }
+
+func TestWrapFormat(t *testing.T) {
+	inner := New("a")
+	outer := Wrap(inner, "b")
+	assert.Equal(t, "b", fmt.Sprintf("%v", outer))
+	assert.Equal(t, "b", fmt.Sprintf("%s", outer))
+	assert.Equal(t, "  b", fmt.Sprintf("%3s", outer))
+	assert.Equal(t, `"b"`, fmt.Sprintf("%q", outer))
+	assert.Equal(t, "b\na", fmt.Sprintf("%+v", outer))
+	assert.Equal(
+		t,
+		`&errors.wrapped{error:&errors.errorString{s:"b"}, wrapped:&errors.errorString{s:"a"}}`,
+		fmt.Sprintf("%#v", outer),
+	)
+	assert.Equal(t, "%!d(*errors.wrapped=b)", fmt.Sprintf("%d", outer))
+}
+
+func deepChain(depth int) error {
+	b := NewBuilder("%d", depth)
+	if depth == 0 {
+		return b.Errorf("bottom")
+	}
+	return b.Wrap(deepChain(depth-1), "layer")
+}
+
+func BenchmarkStackStringDeep(b *testing.B) {
+	b.ReportAllocs()
+	err := deepChain(20)
+	StackString(err) // resolve the lazy FuncInfos
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		StackString(err)
+	}
+}
+
+func TestGroupIsAs(t *testing.T) {
+	errTimeout := New("timeout")
+	g := Group{New("refused"), Wrap(errTimeout, "mirror b")}
+	assert.True(t, Is(g, errTimeout))
+	assert.True(t, Is(Wrap(g, "all failed"), errTimeout))
+	assert.False(t, Is(g, New("timeout")))
+	assert.False(t, Is(Group{}, errTimeout))
+	assert.Equal(t, []error(g), g.Unwrap())
+
+	te := new(testError)
+	var target *testError
+	assert.True(t, As(Wrap(Group{New("a"), te}, "b"), &target))
+	assert.Equal(t, te, target)
+
+	var wrongTarget *multiError
+	assert.False(t, As(g, &wrongTarget))
+}
+
+// countingError counts the calls to its Is method.
+type countingError struct {
+	calls *int
+}
+
+func (ce countingError) Error() string { return "counting" }
+func (ce countingError) Is(error) bool { *ce.calls++; return false }
+
+func TestGroupIsNested(t *testing.T) {
+	calls := 0
+	var err error = countingError{&calls}
+	for i := 0; i < 20; i++ {
+		err = Group{err}
+	}
+	assert.False(t, Is(err, New("other")))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIsAsNonComparable(t *testing.T) {
+	token := New("token")
+	other := New("other")
+	g := Group{token, other}
+	type namedError struct {
+		name string
+		err  error
+	}
+	errs := []namedError{
+		{"comparable", token},
+		{"group", g},
+		{"equal group", Group{token, other}},
+		{"empty group", Group{}},
+		{"nil member group", Group{nil}},
+		{"wrapped comparable", WrapWith(token, other)},
+		{"wrapped group", WrapWith(g, other)},
+		{"group wrapping group", WrapWith(g, g)},
+		{"equal group wrapping group", WrapWith(Group{token, other}, g)},
+		{"group of group wrapping group", Group{WrapWith(g, g)}},
+		{"builder group wrapping group", NewBuilder("").Wrap(g, "%w", g)},
+	}
+	for _, e := range errs {
+		for _, target := range errs {
+			msg := fmt.Sprintf("%s vs %s", e.name, target.name)
+			assert.NotPanics(t, func() { Is(e.err, target.err) }, msg)
+			assert.NotPanics(t, func() {
+				var w Wrapped
+				As(e.err, &w)
+			}, msg)
+			assert.NotPanics(t, func() {
+				var g Group
+				As(e.err, &g)
+			}, msg)
+			if x, ok := e.err.(interface{ Is(error) bool }); ok {
+				assert.NotPanics(t, func() { x.Is(target.err) }, msg)
+			}
+		}
+	}
+
+	assert.True(t, Is(g, Group{token, other}))
+	assert.False(t, Is(g, Group{other, token}))
+	assert.True(t, Is(WrapWith(g, g), WrapWith(Group{token, other}, g)))
+	assert.False(t, Is(WrapWith(g, g), WrapWith(g, token)))
+	assert.True(t, Is(WrapWith(g, other), token))
+	assert.True(t, Is(Group{WrapWith(g, g)}, g))
+	assert.False(t, Is(Group{}, token))
+	assert.False(t, Is(Group{nil}, token))
+}
@@ -0,0 +1,228 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Field is a key/value pair attached to an error by FieldBuilder.With.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// appendFields returns a copy of fields with the alternating keys and values
+// of keyvals added. Keys which are not strings are formatted with fmt.Sprint,
+// and a key without a value gets a nil value.
+func appendFields(fields []Field, keyvals []interface{}) []Field {
+	result := make([]Field, len(fields), len(fields)+(len(keyvals)+1)/2)
+	copy(result, fields)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		var value interface{}
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		result = append(result, Field{key, value})
+	}
+	return result
+}
+
+// Fields returns the fields attached by FieldBuilder.With to all the errors in
+// the
+// Tree of err, merged into one map. If several errors have a field with the
+// same key, the value from the outermost one is kept. Fields returns nil if
+// there are no fields.
+func Fields(err error) map[string]interface{} {
+	var merged map[string]interface{}
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		for _, field := range fieldsOf(node.Err) {
+			if merged == nil {
+				merged = make(map[string]interface{})
+			}
+			if _, ok := merged[field.Key]; !ok {
+				merged[field.Key] = field.Value
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	if tree := Tree(err); tree != nil {
+		walk(tree)
+	}
+	return merged
+}
+
+// fieldsOf returns the Fields() of err or of its Wrapper().
+func fieldsOf(err error) []Field {
+	switch err := err.(type) {
+	case interface{ Fields() []Field }:
+		return err.Fields()
+	case interface{ Wrapper() error }:
+		return fieldsOf(err.Wrapper())
+	}
+	return nil
+}
+
+// fieldsString formats fields for StackString, like `{user="alice" n=3}`.
+func fieldsString(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		format := "%s=%v"
+		if _, ok := field.Value.(string); ok {
+			format = "%s=%q"
+		}
+		parts = append(parts, fmt.Sprintf(format, field.Key, field.Value))
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// fieldList encodes fields as a JSON object, keeping their order.
+type fieldList []Field
+
+// MarshalJSON implements json.Marshaler. Values that cannot be encoded are
+// formatted with fmt.Sprint instead.
+func (fl fieldList) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fl {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(field.Value))
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Numbers are decoded as
+// json.Number, so that they are shown as they were encoded.
+func (fl *fieldList) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil { // {
+		return err
+	}
+	*fl = nil
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var field Field
+		field.Key, _ = token.(string)
+		if err := dec.Decode(&field.Value); err != nil {
+			return err
+		}
+		*fl = append(*fl, field)
+	}
+	_, err := dec.Token() // }
+	return err
+}
+
+// withFields is an error with fields but no location. The fieldsBuilder of
+// BuiltinBuilder and of the builders passed to WithFields make them, and so
+// does UnmarshalChain.
+type withFields struct {
+	error
+	fields []Field
+}
+
+// Unwrap returns the cause of the error, if any.
+func (wf *withFields) Unwrap() error {
+	return Unwrap(wf.error)
+}
+
+// Fields returns the key/value pairs attached to the error.
+func (wf *withFields) Fields() []Field {
+	return wf.fields
+}
+
+// withCause returns wf, or if wf has a cause, wf with a Cause() method.
+func (wf *withFields) withCause() error {
+	if wf.Unwrap() == nil {
+		return wf
+	}
+	return withFieldsCause{wf}
+}
+
+// withFieldsCause is a withFields error with a cause, which has a Cause()
+// method like signaturedCause.
+type withFieldsCause struct {
+	*withFields
+}
+
+// Cause returns the cause of the error.
+func (wfc withFieldsCause) Cause() error {
+	return wfc.Unwrap()
+}
+
+// Format implements fmt.Formatter. %v and %s print the message, %q quotes it,
+// %+v prints the whole chain like StackString, and %#v prints a Go-syntax
+// representation including the fields.
+func (wf *withFields) Format(s fmt.State, verb rune) {
+	formatError(s, verb, wf)
+}
+
+// GoString returns a Go-syntax representation of the error and its fields.
+func (wf *withFields) GoString() string {
+	return fmt.Sprintf(
+		"&errors.withFields{error:%s, fields:%#v}",
+		goStringOf(wf.error),
+		DefaultScrubber.ScrubFields(wf.fields),
+	)
+}
+
+// fieldsBuilder attaches fields to the errors made by another Builder.
+type fieldsBuilder struct {
+	base   Builder
+	fields []Field
+}
+
+// With returns a FieldBuilder that also attaches the given key/value pairs.
+func (fb *fieldsBuilder) With(keyvals ...interface{}) FieldBuilder {
+	return &fieldsBuilder{fb.base, appendFields(fb.fields, keyvals)}
+}
+
+// Errorf is the same as the Errorf of the base Builder, except that the error
+// has the fields. With BuiltinBuilder, which uses fmt.Errorf, %w needs Go
+// 1.13, and several %w verbs need Go 1.20.
+func (fb *fieldsBuilder) Errorf(msg string, args ...interface{}) error {
+	wf := &withFields{fb.base.Errorf(msg, args...), fb.fields}
+	return wf.withCause()
+}
+
+// Wrap is the same as the Wrap of the base Builder, except that the Wrapper()
+// has the fields.
+func (fb *fieldsBuilder) Wrap(
+	err error,
+	msg string,
+	args ...interface{},
+) Wrapped {
+	w := fb.base.Wrap(err, msg, args...)
+	if wr, ok := w.(interface{ Wrapper() error }); ok {
+		return WrapWith(err, &withFields{wr.Wrapper(), fb.fields})
+	}
+	wf := &withFields{w, fb.fields}
+	return wf.withCause().(Wrapped)
+}
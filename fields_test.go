@@ -0,0 +1,129 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderWith(t *testing.T) {
+	b := NewBuilder("%d", 7)
+	err := b.With("user_id", 42, "tenant", "acme").Errorf("denied")
+	assert.Equal(t, "denied", err.Error())
+	assert.Equal(
+		t,
+		map[string]interface{}{"user_id": 42, "tenant": "acme"},
+		Fields(err),
+	)
+	assert.Regexp(
+		t,
+		`\(7\) fields_test\.go:[0-9]+ denied \{user_id=42 tenant="acme"\}$`,
+		StackString(err),
+	)
+
+	// With does not change the original builder
+	assert.Nil(t, Fields(b.Errorf("plain")))
+
+	lazy := NewLazyBuilder("").With("attempt", 1).With("attempt", 2, "dangling")
+	err = lazy.Wrap(err, "retry failed")
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"attempt":  1,
+			"dangling": nil,
+			"user_id":  42,
+			"tenant":   "acme",
+		},
+		Fields(err),
+	)
+
+	err = WithFields(BuiltinBuilder, 3, "x").Wrap(Group{err}, "plain %s", "wrap")
+	assert.Equal(t, "x", Fields(err)["3"])
+	assert.Equal(t, 42, Fields(err)["user_id"])
+	assert.Regexp(t, `^plain wrap \{3="x"\}\n`, StackString(err))
+
+	cause := New("cause")
+	err = WithFields(BuiltinBuilder, "k", "v").Errorf("a: %w", cause)
+	assert.Equal(t, cause, Unwrap(err))
+	assert.Equal(t, "a: cause {k=\"v\"}\ncause", StackString(err))
+}
+
+// prefixBuilder is a Builder from outside of this package, which has no With
+// method.
+type prefixBuilder string
+
+func (pb prefixBuilder) Errorf(msg string, args ...interface{}) error {
+	return fmt.Errorf(string(pb)+msg, args...)
+}
+
+func (pb prefixBuilder) Wrap(err error, msg string, args ...interface{}) Wrapped {
+	return &multiWrapped{pb.Errorf(msg, args...), err}
+}
+
+// multiWrapped is a Wrapped without a Wrapper() method.
+type multiWrapped struct {
+	error
+	cause error
+}
+
+func (mw *multiWrapped) Unwrap() error { return mw.cause }
+
+func TestWithFields(t *testing.T) {
+	var b Builder = prefixBuilder("pkg: ")
+	fb := WithFields(b, "k", 1)
+	err := fb.Errorf("failed")
+	assert.Equal(t, "pkg: failed", err.Error())
+	assert.Equal(t, map[string]interface{}{"k": 1}, Fields(err))
+
+	cause := New("cause")
+	err = fb.With("n", 2).Wrap(cause, "retry")
+	assert.Equal(t, "pkg: retry", err.Error())
+	assert.True(t, Is(err, cause))
+	assert.Equal(t, map[string]interface{}{"k": 1, "n": 2}, Fields(err))
+
+	nb := NewBuilder("")
+	err = WithFields(nb, "k", 1).Errorf("located")
+	assert.Equal(t, map[string]interface{}{"k": 1}, Fields(err))
+	assert.NotNil(t, Origin(err))
+}
+
+func TestWithFieldsFormat(t *testing.T) {
+	cause := New("cause")
+	err := WithFields(BuiltinBuilder, "k", "v").Errorf("a: %w", cause)
+	assert.Equal(t, "a: cause", fmt.Sprintf("%v", err))
+	assert.Equal(t, `"a: cause"`, fmt.Sprintf("%q", err))
+	assert.Equal(t, StackString(err), fmt.Sprintf("%+v", err))
+	assert.Contains(t, fmt.Sprintf("%+v", err), "{k=\"v\"}\ncause")
+	assert.Regexp(
+		t,
+		`^&errors\.withFields\{error:.*, fields:\[\]errors\.Field\{errors\.Field\{Key:"k", Value:"v"\}\}\}$`,
+		fmt.Sprintf("%#v", err),
+	)
+}
+
+func TestFieldsJSON(t *testing.T) {
+	orig := NewBuilder("").With("user_id", 42, "tenant", "acme", "ch", make(chan int)).
+		Wrap(WithFields(BuiltinBuilder, "n", 1.5).Errorf("inner"), "outer")
+	buf, err := MarshalChain(orig)
+	require.NoError(t, err)
+	var layers []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &layers))
+	fields := layers[0]["fields"].(map[string]interface{})
+	assert.Equal(t, float64(42), fields["user_id"])
+	assert.Equal(t, "acme", fields["tenant"])
+	assert.IsType(t, "", fields["ch"])
+	assert.Equal(t, map[string]interface{}{"n": 1.5}, layers[1]["fields"])
+
+	chain, err := UnmarshalChain(buf)
+	require.NoError(t, err)
+	assert.Equal(t, json.Number("42"), Fields(chain)["user_id"])
+	assert.Equal(t, "acme", Fields(chain)["tenant"])
+	assert.Equal(t, json.Number("1.5"), Fields(chain)["n"])
+
+	var decoded fieldList
+	require.NoError(t, json.Unmarshal([]byte(`{"b": 1, "a": [true]}`), &decoded))
+	assert.Equal(t, fieldList{{"b", json.Number("1")}, {"a", []interface{}{true}}}, decoded)
+}
@@ -0,0 +1,450 @@
+package errors
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultTemplate is the layout of a located layer used when
+// Formatter.Template is empty.
+const DefaultTemplate = "{func}{args} {location} {message}{fields}"
+
+// PathStyle selects how a Formatter shows source file paths.
+type PathStyle int
+
+const (
+	// BasePath shows only the file name, like "client.go".
+	BasePath PathStyle = iota
+
+	// FullPath shows the path as reported by FuncInfo.File(). Unless the
+	// binary was built with -trimpath, it includes the directories of the
+	// build machine; use Root to shorten it, or ModulePath instead.
+	FullPath
+
+	// ModulePath shows the path within the module, after the module path
+	// abbreviated by Formatter.Modules, like "~/client/userprofile.go". See
+	// ModuleFile.
+	ModulePath
+)
+
+// Formatter renders error chains as text. The zero value is usable, and
+// differs from DefaultFormatter only in that module paths are not
+// abbreviated. Build one formatter for terminals and another for
+// log files by setting different options, or start from NewTerminalFormatter.
+type Formatter struct {
+	// Template lays out each layer that has a FuncInfo(). The placeholders
+	// {func}, {args}, {file}, {line}, {message} and {fields} are replaced by
+	// the parts of the layer, and {location} by "{file}:{line}", which is
+	// hyperlinked as a whole. {args} includes the surrounding parenthesis, and
+	// is empty if the error has no ArgStringer() or if HideArgs is set.
+	// {fields} lists the key/value pairs from FieldBuilder.With after a space, or is
+	// empty if there are none. If empty, DefaultTemplate is used.
+	Template string
+
+	// Path selects how {file} is shown.
+	Path PathStyle
+
+	// Root is a directory, like the root of a repository, which FullPath
+	// paths are shown relative to. Files outside of Root are shown in full.
+	// If empty, paths are not shortened.
+	Root string
+
+	// Modules abbreviates module paths in function names. If nil, function
+	// names are shown in full.
+	Modules *ModuleTable
+
+	// HideArgs leaves out the {args} of every layer.
+	HideArgs bool
+
+	// Raw shows messages, args and fields verbatim. By default, newlines,
+	// control characters and terminal escape sequences inside them are
+	// escaped like "\n" and "\x1b", backslashes are doubled, and
+	// bidirectional overrides are escaped like "\u202e", so that they cannot
+	// forge extra layers, corrupt terminals or reorder the text.
+	Raw bool
+
+	// Separator goes between layers. If empty, "\n" is used.
+	Separator string
+
+	// Branches draws the members of a Group, and the causes of other errors
+	// that wrap several errors. If zero, BoxTreeStyle is used.
+	Branches TreeStyle
+
+	// Scrubber removes sensitive text from every layer. If nil, the
+	// DefaultScrubber at the time of formatting is used. Set it to
+	// new(Scrubber) to remove nothing.
+	Scrubber *Scrubber
+
+	// Colors highlights the parts of each layer with ANSI escape codes. If
+	// nil, no colors are used.
+	Colors *ColorScheme
+
+	// Link wraps each {location} or {file} in an OSC 8 hyperlink, which
+	// terminals let you click. In this template, {file} is the absolute path
+	// from FuncInfo.File(), which already starts with a slash, and {line} is
+	// the line number, like FileLink or "vscode://file{file}:{line}". If
+	// empty, no links are made.
+	Link string
+
+	// SourceLines shows that many lines of source code before and after the
+	// location of each layer, with the failing line marked by ">". Only files
+	// inside Root are read, or if Root is empty, files of the main module.
+	// Layers decoded by UnmarshalChain, and layers whose source cannot be
+	// read, are shown without it. If 0, no source is shown.
+	SourceLines int
+
+	// Source reads the source code for SourceLines. If nil,
+	// DefaultSourceCache is used, which reads files from the disk.
+	Source SourceReader
+
+	// MaxDepth limits how many layers of a chain are shown. Further layers
+	// are summarized in one line. If 0, all layers are shown.
+	MaxDepth int
+}
+
+// TreeStyle holds the prefixes drawn before the branches of a Tree.
+type TreeStyle struct {
+	// Branch and LastBranch prefix the first layer of each branch, and of
+	// the last branch.
+	Branch, LastBranch string
+
+	// Continue and LastContinue prefix the following layers of each branch,
+	// and of the last branch.
+	Continue, LastContinue string
+}
+
+var (
+	// BoxTreeStyle draws branches with box-drawing characters.
+	BoxTreeStyle = TreeStyle{"├─ ", "└─ ", "│  ", "   "}
+
+	// ASCIITreeStyle draws branches with ASCII characters only.
+	ASCIITreeStyle = TreeStyle{"|- ", "`- ", "|  ", "   "}
+)
+
+// DefaultFormatter is the Formatter used by StackString, StackStringAt and
+// the %+v verb. It abbreviates module paths with DefaultModuleTable, and
+// scrubs layers with DefaultScrubber.
+var DefaultFormatter = &Formatter{Modules: DefaultModuleTable}
+
+// StackString recursively unwraps the given error and stringifies all the
+// errors in its Tree. Linear chains of errors are shown one layer after
+// another, and the members of a Group or of another error wrapping several
+// errors are shown as branches below it.
+//
+// The stringification adds location prefixes to errors that additionally
+// implement `FuncInfo() FuncInfo` and optionally `ArgStringer() interface{
+// String() string }`.
+//
+// The stringification can be overridden if the error implements `StackString()
+// string`.
+//
+// If DetectGaps was on when the errors were made, functions between layers
+// that did not add their own layer are listed between those layers.
+//
+// Errors that implement `Callers() []uintptr`, like those from WithStack, are
+// not printed as layers. Instead, the frames recorded by the innermost such
+// error are printed after the last layer of its chain.
+func (f *Formatter) StackString(err error) string {
+	return strings.Join(f.treeLines(Tree(err), 0), f.separator())
+}
+
+// treeLines formats node and its descendants, one layer per element. depth is
+// the number of layers shown above node.
+func (f *Formatter) treeLines(node *Node, depth int) []string {
+	if node == nil {
+		return nil
+	}
+	var lines []string
+	var chain []error
+	var pcs, prevFingerprint []uintptr
+	for {
+		err := node.Err
+		chain = append(chain, err)
+		if c, ok := err.(interface{ Callers() []uintptr }); ok {
+			pcs = c.Callers()
+		} else if _, ok := err.(Group); !ok { // groups only show their members
+			if f.MaxDepth > 0 && depth >= f.MaxDepth {
+				return append(lines, f.moreLine(countLayers(node)))
+			}
+			depth++
+			if funcInfoOf(err) != nil {
+				fingerprint := fingerprintOf(err)
+				if gap := gapFrames(prevFingerprint, fingerprint); len(gap) != 0 {
+					lines = append(lines, f.gapLine(gap))
+				}
+				prevFingerprint = fingerprint
+			}
+			lines = append(lines, f.StackStringAt(err))
+		}
+		if len(node.Children) != 1 {
+			break
+		}
+		node = node.Children[0]
+	}
+	if pcs != nil {
+		for _, frame := range uncoveredFrames(pcs, chain) {
+			lines = append(lines, "\t"+f.location(
+				frame.Function,
+				frame.File,
+				frame.Line,
+			))
+		}
+	}
+	if f.MaxDepth > 0 && depth >= f.MaxDepth && len(node.Children) != 0 {
+		n := 0
+		for _, child := range node.Children {
+			n += countLayers(child)
+		}
+		return append(lines, f.moreLine(n))
+	}
+	style := f.branches()
+	sep := f.separator()
+	for i, child := range node.Children {
+		branch, cont := style.Branch, style.Continue
+		if i == len(node.Children)-1 {
+			branch, cont = style.LastBranch, style.LastContinue
+		}
+		for j, line := range f.treeLines(child, depth) {
+			prefix := cont
+			if j == 0 {
+				prefix = branch
+			}
+			lines = append(lines, prefix+strings.Replace(line, sep, sep+cont, -1))
+		}
+	}
+	return lines
+}
+
+// StackStringAt returns one level of StackString.
+func (f *Formatter) StackStringAt(err error) string {
+	switch err := err.(type) {
+	case Group:
+		return f.group(err)
+	case interface{ StackString() string }:
+		return f.scrubber().Scrub(err.StackString())
+	case interface {
+		error
+		FuncInfo() FuncInfo
+	}:
+		fi := err.FuncInfo()
+		if fi == nil {
+			break
+		}
+		var args string
+		if as, ok := err.(interface {
+			ArgStringer() interface{ String() string }
+		}); ok && !f.HideArgs {
+			args = "(" + f.text(as.ArgStringer().String()) + ")"
+		}
+		return f.layer(fi, args, f.text(layerMessage(err)), f.fields(err)) +
+			f.snippet(fi)
+	case interface {
+		error
+		Wrapper() error
+	}:
+		return f.StackStringAt(err.Wrapper())
+	}
+	return paint(f.colors().Message, f.text(err.Error())) + f.fields(err)
+}
+
+// group formats the members of a Group as the branches of a Tree.
+func (f *Formatter) group(g Group) string {
+	return strings.Join(f.treeLines(Tree(g), 0), f.separator())
+}
+
+// layer fills in the Template for a layer at fi.
+func (f *Formatter) layer(fi FuncInfo, args, message, fields string) string {
+	template := f.Template
+	if template == "" {
+		template = DefaultTemplate
+	}
+	colors := f.colors()
+	file, line := fi.File(), fi.Line()
+	lineText := strconv.Itoa(line)
+	return strings.NewReplacer(
+		"{func}", paint(colors.Func, f.funcNameOf(fi)),
+		"{args}", paint(colors.Args, args),
+		"{location}", f.link(file, line, paint(
+			colors.Location,
+			f.file(fi)+":"+lineText,
+		)),
+		"{file}", f.link(file, line, paint(colors.Location, f.file(fi))),
+		"{line}", paint(colors.Location, lineText),
+		"{message}", paint(colors.Message, message),
+		"{fields}", fields,
+	).Replace(template)
+}
+
+// text scrubs and escapes text from an error.
+func (f *Formatter) text(text string) string {
+	return f.escape(f.scrubber().Scrub(text))
+}
+
+// fields formats the Fields() of a layer after a space.
+func (f *Formatter) fields(err error) string {
+	fields := fieldsOf(err)
+	if len(fields) == 0 {
+		return ""
+	}
+	return " " + paint(
+		f.colors().Fields,
+		f.text(fieldsString(f.scrubber().ScrubFields(fields))),
+	)
+}
+
+// location formats a stack frame which is not a layer.
+func (f *Formatter) location(funcName, file string, line int) string {
+	colors := f.colors()
+	return paint(colors.Func, f.funcName(funcName)) + " " + f.link(
+		file,
+		line,
+		paint(colors.Location, fmt.Sprintf(
+			"%s:%d",
+			f.file(&funcInfo{file: file, funcName: funcName, line: line}),
+			line,
+		)),
+	)
+}
+
+// gapLine describes frames which were skipped between two layers.
+func (f *Formatter) gapLine(gap []callerFrame) string {
+	names := make([]string, 0, len(gap))
+	for _, frame := range gap {
+		names = append(names, f.funcName(frame.Function))
+	}
+	noun := "frames"
+	if len(gap) == 1 {
+		noun = "frame"
+	}
+	return fmt.Sprintf(
+		"... (%d %s not wrapped: %s)",
+		len(gap),
+		noun,
+		strings.Join(names, ", "),
+	)
+}
+
+// moreLine summarizes n layers which are beyond MaxDepth.
+func (f *Formatter) moreLine(n int) string {
+	noun := "layers"
+	if n == 1 {
+		noun = "layer"
+	}
+	return fmt.Sprintf("... (%d more %s)", n, noun)
+}
+
+// countLayers returns the number of layers that StackString would show for
+// node and its descendants.
+func countLayers(node *Node) int {
+	n := 0
+	switch node.Err.(type) {
+	case Group, interface{ Callers() []uintptr }:
+	default:
+		n++
+	}
+	for _, child := range node.Children {
+		n += countLayers(child)
+	}
+	return n
+}
+
+// funcName formats a function name from the runtime for display.
+func (f *Formatter) funcName(name string) string {
+	pretty := PrettyFuncName(name)
+	if f.Modules == nil {
+		return pretty
+	}
+	return f.Modules.Abbreviate(pretty)
+}
+
+// funcNameOf formats the function name of fi for display, abbreviating the
+// main module of the process where fi was recorded.
+func (f *Formatter) funcNameOf(fi FuncInfo) string {
+	pretty := PrettyFuncName(fi.FuncName())
+	if f.Modules == nil {
+		return pretty
+	}
+	return f.Modules.abbreviateWithMain(pretty, mainModuleOf(fi))
+}
+
+// file formats a source file path according to the PathStyle.
+func (f *Formatter) file(fi FuncInfo) string {
+	switch f.Path {
+	case FullPath:
+		return f.rootPath(fi.File())
+	case ModulePath:
+		mf := ModuleFileOf(fi)
+		if mf.Module == "" {
+			return mf.Path
+		}
+		name := mf.Module + "/" + mf.Path
+		if f.Modules == nil {
+			return name
+		}
+		return f.Modules.abbreviateWithMain(name, mainModuleOf(fi))
+	}
+	return path.Base(fi.File())
+}
+
+func (f *Formatter) separator() string {
+	if f.Separator == "" {
+		return "\n"
+	}
+	return f.Separator
+}
+
+func (f *Formatter) branches() TreeStyle {
+	if f.Branches == (TreeStyle{}) {
+		return BoxTreeStyle
+	}
+	return f.Branches
+}
+
+func (f *Formatter) scrubber() *Scrubber {
+	if f.Scrubber == nil {
+		return DefaultScrubber
+	}
+	return f.Scrubber
+}
+
+// escape escapes control characters in text, unless Raw is set.
+func (f *Formatter) escape(text string) string {
+	if f.Raw {
+		return text
+	}
+	return escapeControl(text)
+}
+
+// escapeControl replaces newlines, other control characters, line separators
+// and bidirectional overrides with Go escape sequences, like "\n", "\x1b" and
+// "\u202e". Backslashes are doubled, so that escaped text cannot be mistaken
+// for an escape sequence.
+func escapeControl(text string) string {
+	if strings.IndexFunc(text, isEscaped) < 0 {
+		return text
+	}
+	var b strings.Builder
+	for _, r := range text {
+		if !isEscaped(r) {
+			b.WriteRune(r)
+			continue
+		}
+		quoted := strconv.QuoteRune(r)
+		b.WriteString(quoted[1 : len(quoted)-1])
+	}
+	return b.String()
+}
+
+// isEscaped returns whether escapeControl escapes r.
+func isEscaped(r rune) bool {
+	switch {
+	case unicode.IsControl(r), r == '\\', r == '\u2028', r == '\u2029':
+		return true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func formatterTestChain() error {
+	b := NewBuilder("%q", "x")
+	return b.Wrap(Wrap(New("c"), "b"), "a")
+}
+
+func TestFormatterZeroValue(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	var f Formatter
+	assert.Regexp(
+		t,
+		`^github\.com/chaimleib/errors\.formatterTestChain\("x"\) formatter_test\.go:[0-9]+ a\nb\nc$`,
+		f.StackString(formatterTestChain()),
+	)
+	assert.Regexp(
+		t,
+		`^~\.formatterTestChain\("x"\) formatter_test\.go:[0-9]+ a\nb\nc$`,
+		DefaultFormatter.StackString(formatterTestChain()),
+	)
+	assert.Equal(t, "", f.StackString(nil))
+}
+
+func TestFormatterOptions(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	f := &Formatter{
+		Template:  "{message} at {func}{args} ({file}:{line})",
+		Path:      FullPath,
+		Modules:   DefaultModuleTable,
+		HideArgs:  true,
+		Separator: " | ",
+		MaxDepth:  2,
+	}
+	assert.Regexp(
+		t,
+		`^a at ~\.formatterTestChain \(/[^ ]+/formatter_test\.go:[0-9]+\) \| b \| \.\.\. \(1 more layer\)$`,
+		f.StackString(formatterTestChain()),
+	)
+}
+
+func TestFormatterGroup(t *testing.T) {
+	f := &Formatter{Separator: " / "}
+	g := Group{New("a"), Wrap(New("c"), "b")}
+	assert.Equal(t, "├─ a / └─ b /    c", f.StackString(g))
+	assert.Equal(t, "a", f.StackString(Group{New("a")}))
+	assert.Equal(t, "", f.StackStringAt(Group{}))
+}
+
+func TestFormatterEscape(t *testing.T) {
+	user := "bob\nmain.forged() forged.go:1 fake layer"
+	b := NewBuilder("%q, %s", "x", "\x1b[31mred\x1b[0m")
+	err := b.Wrap(New("cause"), "no user "+user)
+	out := StackString(err)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(
+		t,
+		`\("x", \\x1b\[31mred\\x1b\[0m\) formatter_test\.go:[0-9]+ no user bob\\nmain\.forged\(\) forged\.go:1 fake layer$`,
+		lines[0],
+	)
+	assert.Equal(t, "cause", lines[1])
+
+	f := &Formatter{Raw: true}
+	assert.Len(t, strings.Split(f.StackString(err), "\n"), 3)
+	assert.Contains(t, f.StackString(err), "\x1b[31m")
+}
+
+func TestEscapeControl(t *testing.T) {
+	assert.Equal(t, "plain é", escapeControl("plain é"))
+	assert.Equal(t, `C:\\dir \\n`, escapeControl(`C:\dir \n`))
+	assert.Equal(t, `a\u202eb\u2066c\u2069`, escapeControl("a\u202eb\u2066c\u2069"))
+	assert.Equal(t, `a\nb\r\tc\x00\x7f\u2028\u0085`, escapeControl("a\nb\r\tc\x00\x7f\u2028\u0085"))
+}
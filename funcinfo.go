@@ -1,7 +1,9 @@
 package errors
 
 import (
+	"fmt"
 	"runtime"
+	"sync"
 )
 
 type funcInfo struct {
@@ -26,6 +28,11 @@ func (fi *funcInfo) Line() int {
 	return fi.line
 }
 
+// ModuleFile gives the file within its module.
+func (fi *funcInfo) ModuleFile() ModuleFile {
+	return newModuleFile(fi.file, fi.funcName)
+}
+
 // FuncInfo identifies a line of code, as provided by go runtime package
 // functions.
 type FuncInfo interface {
@@ -36,18 +43,117 @@ type FuncInfo interface {
 
 // NewFuncInfo ascends the number of stack frames indicated by calldepth and
 // returns a FuncInfo describing the location of that line of code. A calldepth
-// of 0 refers to the invocation of NewFunInfo itself.
+// of 0 refers to the invocation of NewFunInfo itself. Frames of inlined
+// functions are counted and reported as if they had not been inlined.
 func NewFuncInfo(calldepth int) FuncInfo {
-	var pc uintptr
-	var ok bool
+	pcs := make([]uintptr, calldepth+2)
+	frame, ok := frameAt(pcs[:runtime.Callers(1, pcs)], calldepth+1)
+	if !ok {
+		return nil
+	}
 	fi := new(funcInfo)
-	pc, fi.file, fi.line, ok = runtime.Caller(calldepth + 1)
+	fi.set(frame)
+	return fi
+}
+
+// frameAt returns the frame that is skip frames above the first of pcs.
+// The frames are counted by runtime.CallersFrames, rather than by the skip
+// argument of runtime.Callers, since only CallersFrames is documented to
+// account for inlined functions. One program counter can stand for several
+// inlined frames, so pcs needs at most skip+1 of them.
+func frameAt(pcs []uintptr, skip int) (runtime.Frame, bool) {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if skip == 0 {
+			return frame, frame.PC != 0
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+		skip--
+	}
+}
+
+// set sets the location of fi from frame.
+func (fi *funcInfo) set(frame runtime.Frame) {
+	fi.file = frame.File
+	fi.funcName = frame.Function
+	fi.line = frame.Line
 	if fi.file == "" {
 		fi.file = "?file?"
 	}
-	if !ok {
-		return nil
+}
+
+// lazyFuncInfo stores only program counters, and resolves them to a location
+// the first time it is asked for one.
+type lazyFuncInfo struct {
+	pcs  []uintptr // the stack from NewLazyFuncInfo to the location
+	skip int       // the number of frames in pcs above the location
+	buf  [3]uintptr
+	once sync.Once
+	fi   funcInfo
+}
+
+// NewLazyFuncInfo is like NewFuncInfo, except that it only records program
+// counters. The file, line and function name are looked up on first access,
+// and then cached. This is cheaper for errors that are never printed.
+func NewLazyFuncInfo(calldepth int) FuncInfo {
+	lfi := &lazyFuncInfo{skip: calldepth + 1}
+	lfi.pcs = lfi.buf[:]
+	if len(lfi.buf) < calldepth+2 {
+		lfi.pcs = make([]uintptr, calldepth+2)
 	}
-	fi.funcName = runtime.FuncForPC(pc).Name()
-	return fi
+	lfi.pcs = lfi.pcs[:runtime.Callers(1, lfi.pcs)]
+	if len(lfi.pcs) < calldepth+2 {
+		// The stack may still be deep enough if some frames were inlined.
+		if _, ok := frameAt(lfi.pcs, lfi.skip); !ok {
+			return nil
+		}
+	}
+	return lfi
+}
+
+func (lfi *lazyFuncInfo) resolved() *funcInfo {
+	lfi.once.Do(func() {
+		frame, _ := frameAt(lfi.pcs, lfi.skip)
+		lfi.fi.set(frame)
+	})
+	return &lfi.fi
+}
+
+// File gives the absolute path to the go source file.
+func (lfi *lazyFuncInfo) File() string {
+	return lfi.resolved().File()
+}
+
+// FuncName gives the fully-qualified identifier of the function. This includes
+// the enclosing package name, with child packaged separated with slashes, and
+// the function name appended after a dot.
+func (lfi *lazyFuncInfo) FuncName() string {
+	return lfi.resolved().FuncName()
+}
+
+// Line gives the line number.
+func (lfi *lazyFuncInfo) Line() int {
+	return lfi.resolved().Line()
+}
+
+// ModuleFile gives the file within its module.
+func (lfi *lazyFuncInfo) ModuleFile() ModuleFile {
+	return lfi.resolved().ModuleFile()
+}
+
+// goStringFuncInfo returns a Go-syntax representation of fi which does not
+// depend on the FuncInfo implementation.
+func goStringFuncInfo(fi FuncInfo) string {
+	if fi == nil {
+		return "errors.FuncInfo(nil)"
+	}
+	return fmt.Sprintf(
+		"errors.FuncInfo{FuncName:%q, File:%q, Line:%d}",
+		fi.FuncName(),
+		fi.File(),
+		fi.Line(),
+	)
 }
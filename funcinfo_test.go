@@ -1,9 +1,13 @@
 package errors
 
 import (
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFuncInfo(t *testing.T) {
@@ -13,3 +17,90 @@ func TestFuncInfo(t *testing.T) {
 	assert.Contains(t, fi.File(), "/funcinfo_test.go")
 	assert.LessOrEqual(t, 10, fi.Line())
 }
+
+// The inlined functions below are small enough for the compiler to inline
+// them into inlineHelper, which is kept out of line so that the inlined frames
+// have a real caller to be confused with. The lazy variants record program
+// counters, which show whether they were inlined. Current Go versions also
+// resolve inlined frames correctly through runtime.Caller and FuncForPC, so
+// this only guards the behavior, and cannot tell those apart from
+// runtime.CallersFrames.
+func inlinedLeaf() FuncInfo       { return NewFuncInfo(0) }
+func inlinedLazyLeaf() FuncInfo   { return NewLazyFuncInfo(0) }
+func inlinedCaller() FuncInfo     { return NewFuncInfo(1) }
+func inlinedLazyCaller() FuncInfo { return NewLazyFuncInfo(1) }
+
+//go:noinline
+func inlineHelper() (leaf, caller FuncInfo, line int, lazy []FuncInfo) {
+	leaf, lazyLeaf := inlinedLeaf(), inlinedLazyLeaf()
+	caller, lazyCaller := inlinedCaller(), inlinedLazyCaller()
+	line = NewFuncInfo(0).Line() - 1
+	return leaf, caller, line, []FuncInfo{lazyLeaf, lazyCaller}
+}
+
+func TestFuncInfoInlined(t *testing.T) {
+	leaf, caller, line, lazy := inlineHelper()
+	helperEntry := reflect.ValueOf(inlineHelper).Pointer()
+	for _, fi := range lazy {
+		// The frame above NewLazyFuncInfo has the entry point of the function
+		// that it was inlined into.
+		pcs := fi.(*lazyFuncInfo).pcs
+		if len(pcs) < 2 || runtime.FuncForPC(pcs[1]).Entry() != helperEntry {
+			t.Skip("the compiler did not inline the helpers")
+		}
+	}
+
+	require.NotNil(t, leaf)
+	assert.True(t, strings.HasSuffix(leaf.FuncName(), "errors.inlinedLeaf"), leaf.FuncName())
+	assert.Contains(t, leaf.File(), "/funcinfo_test.go")
+	assert.True(t, strings.HasSuffix(lazy[0].FuncName(), "errors.inlinedLazyLeaf"), lazy[0].FuncName())
+
+	require.NotNil(t, caller)
+	assert.True(t, strings.HasSuffix(caller.FuncName(), "errors.inlineHelper"), caller.FuncName())
+	assert.Contains(t, caller.File(), "/funcinfo_test.go")
+	assert.Equal(t, line, caller.Line())
+	assert.Equal(t, lazy[1].FuncName(), caller.FuncName())
+	assert.Equal(t, lazy[1].Line(), caller.Line())
+}
+
+func TestFuncInfoTooDeep(t *testing.T) {
+	assert.Nil(t, NewFuncInfo(1000))
+}
+
+func TestLazyFuncInfo(t *testing.T) {
+	eager, lazy := NewFuncInfo(0), NewLazyFuncInfo(0)
+	assert.Equal(t, eager.FuncName(), lazy.FuncName())
+	assert.Equal(t, eager.File(), lazy.File())
+	assert.Equal(t, eager.Line(), lazy.Line())
+
+	fi := inlinedLazyFuncInfo()
+	assert.True(t, strings.HasSuffix(fi.FuncName(), "errors.inlinedLazyFuncInfo"), fi.FuncName())
+
+	assert.Nil(t, NewLazyFuncInfo(1000))
+}
+
+func inlinedLazyFuncInfo() FuncInfo { return NewLazyFuncInfo(0) }
+
+var benchFuncInfo FuncInfo
+
+func BenchmarkNewFuncInfo(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchFuncInfo = NewFuncInfo(0)
+	}
+}
+
+func BenchmarkNewLazyFuncInfo(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchFuncInfo = NewLazyFuncInfo(0)
+	}
+}
+
+func BenchmarkNewLazyFuncInfoResolved(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchFuncInfo = NewLazyFuncInfo(0)
+		benchFuncInfo.Line()
+	}
+}
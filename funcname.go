@@ -0,0 +1,136 @@
+package errors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsedFuncName holds the parts of a function name as reported by the
+// runtime, like the result of FuncInfo.FuncName().
+type ParsedFuncName struct {
+	// Package is the import path of the package, like "github.com/a/client".
+	Package string
+
+	// Receiver is the type a method is declared on, without any pointer or
+	// type parameters, like "Client". It is empty for plain functions.
+	Receiver string
+
+	// PointerReceiver is true if the method is declared on a pointer receiver.
+	PointerReceiver bool
+
+	// Name is the name of the function or method, without type parameters,
+	// like "Authenticate".
+	Name string
+
+	// Closure numbers the nested function literals leading to the function,
+	// outermost first. For "Authenticate.func2.1", it is [2 1]. It is empty if
+	// the function is not a function literal.
+	Closure []int
+}
+
+// ParseFuncName splits a function name from the runtime into its parts.
+func ParseFuncName(name string) ParsedFuncName {
+	var p ParsedFuncName
+	rest := name
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		if dot := strings.Index(name[slash:], "."); dot >= 0 {
+			p.Package, rest = name[:slash+dot], name[slash+dot+1:]
+		}
+	} else if dot := strings.Index(name, "."); dot >= 0 {
+		p.Package, rest = name[:dot], name[dot+1:]
+	}
+	// The runtime escapes dots in the last element of the import path.
+	p.Package = strings.Replace(p.Package, "%2e", ".", -1)
+	rest = strings.Replace(rest, "[...]", "", -1)
+
+	if strings.HasPrefix(rest, "(") {
+		if end := strings.Index(rest, ")"); end >= 0 {
+			p.Receiver = rest[1:end]
+			if strings.HasPrefix(p.Receiver, "*") {
+				p.PointerReceiver = true
+				p.Receiver = p.Receiver[1:]
+			}
+			rest = strings.TrimPrefix(rest[end+1:], ".")
+		}
+	}
+
+	var names []string
+	for _, seg := range strings.Split(rest, ".") {
+		if strings.HasPrefix(seg, "func") {
+			if n, err := strconv.Atoi(seg[len("func"):]); err == nil {
+				p.Closure = append(p.Closure, n)
+				continue
+			}
+		}
+		if n, err := strconv.Atoi(seg); err == nil && len(p.Closure) != 0 {
+			p.Closure = append(p.Closure, n)
+			continue
+		}
+		if seg == "" {
+			continue
+		}
+		if len(p.Closure) != 0 {
+			// not a closure after all, like a wrapper inside a closure
+			for _, n := range p.Closure {
+				names = append(names, "func"+strconv.Itoa(n))
+			}
+			p.Closure = nil
+		}
+		names = append(names, seg)
+	}
+	if p.Receiver == "" && len(names) == 2 && !isGenerated(names[1]) {
+		// value receiver, like pkg.T.Method
+		p.Receiver, names = names[0], names[1:]
+	}
+	p.Name = strings.Join(names, ".")
+	return p
+}
+
+// String formats the function name for display, like
+// "example.com/client.(*Client).Authenticate → closure #2.1".
+func (p ParsedFuncName) String() string {
+	var b strings.Builder
+	if p.Package != "" {
+		b.WriteString(p.Package)
+		b.WriteByte('.')
+	}
+	switch {
+	case p.PointerReceiver:
+		fmt.Fprintf(&b, "(*%s).", p.Receiver)
+	case p.Receiver != "":
+		fmt.Fprintf(&b, "%s.", p.Receiver)
+	}
+	b.WriteString(p.Name)
+	if len(p.Closure) != 0 {
+		b.WriteString(" → closure #")
+		for i, n := range p.Closure {
+			if i != 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(strconv.Itoa(n))
+		}
+	}
+	return b.String()
+}
+
+// PrettyFuncName formats a function name from the runtime for display. See
+// ParsedFuncName.String.
+func PrettyFuncName(name string) string {
+	return ParseFuncName(name).String()
+}
+
+// isGenerated returns whether a part of a function name comes from the
+// compiler rather than from the source, like "0" in "init.0", or
+// "deferwrap1".
+func isGenerated(s string) bool {
+	if _, err := strconv.Atoi(s); err == nil {
+		return true
+	}
+	for _, prefix := range []string{"func", "deferwrap", "gowrap"} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFuncName(t *testing.T) {
+	type testCase struct {
+		raw    string
+		parsed ParsedFuncName
+		pretty string
+	}
+	cases := []testCase{
+		{"main.main", ParsedFuncName{Package: "main", Name: "main"}, "main.main"},
+		{
+			"example.com/client.(*Client).Authenticate.func2.1",
+			ParsedFuncName{
+				Package:         "example.com/client",
+				Receiver:        "Client",
+				PointerReceiver: true,
+				Name:            "Authenticate",
+				Closure:         []int{2, 1},
+			},
+			"example.com/client.(*Client).Authenticate → closure #2.1",
+		},
+		{
+			"example.com/client.Client.Name",
+			ParsedFuncName{Package: "example.com/client", Receiver: "Client", Name: "Name"},
+			"example.com/client.Client.Name",
+		},
+		{
+			"example.com/slices.Map[...]",
+			ParsedFuncName{Package: "example.com/slices", Name: "Map"},
+			"example.com/slices.Map",
+		},
+		{
+			"example.com/slices.(*List[...]).Push.func1",
+			ParsedFuncName{
+				Package:         "example.com/slices",
+				Receiver:        "List",
+				PointerReceiver: true,
+				Name:            "Push",
+				Closure:         []int{1},
+			},
+			"example.com/slices.(*List).Push → closure #1",
+		},
+		{
+			"gopkg.in/yaml%2ev2.Unmarshal",
+			ParsedFuncName{Package: "gopkg.in/yaml.v2", Name: "Unmarshal"},
+			"gopkg.in/yaml.v2.Unmarshal",
+		},
+		{
+			"example.com/pkg.init.0",
+			ParsedFuncName{Package: "example.com/pkg", Name: "init.0"},
+			"example.com/pkg.init.0",
+		},
+		{
+			"example.com/pkg.glob..func1",
+			ParsedFuncName{Package: "example.com/pkg", Name: "glob", Closure: []int{1}},
+			"example.com/pkg.glob → closure #1",
+		},
+		{
+			"example.com/pkg.F.func1.deferwrap1",
+			ParsedFuncName{Package: "example.com/pkg", Name: "F.func1.deferwrap1"},
+			"example.com/pkg.F.func1.deferwrap1",
+		},
+		{"runtime.goexit", ParsedFuncName{Package: "runtime", Name: "goexit"}, "runtime.goexit"},
+	}
+	for i, c := range cases {
+		msg := fmt.Sprintf("case %d %q", i, c.raw)
+		assert.Equal(t, c.parsed, ParseFuncName(c.raw), msg)
+		assert.Equal(t, c.pretty, PrettyFuncName(c.raw), msg)
+	}
+}
+
+func TestStackStringClosure(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	var err error
+	func() {
+		err = NewBuilder("").Errorf("in closure")
+	}()
+	assert.Regexp(
+		t,
+		`^~\.TestStackStringClosure → closure #1\(\) funcname_test\.go:[0-9]+ in closure$`,
+		StackString(err),
+	)
+}
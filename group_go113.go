@@ -0,0 +1,32 @@
+// +build go1.13,!go1.20
+
+package errors
+
+// Is returns whether the target is a Group with equal members, or whether any
+// member of the group, or any member's cause chain, matches the target. The
+// Is of Go 1.13 to 1.19 does not know `Unwrap() []error`, so the group walks
+// its members itself.
+func (g Group) Is(target error) bool {
+	if equal(g, target) {
+		return true
+	}
+	for _, err := range g {
+		if Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As assigns the first compatible error it finds among the members of the
+// group and their cause chains to the target, and returns true if successful.
+// The As of Go 1.13 to 1.19 does not know `Unwrap() []error`, so the group
+// walks its members itself.
+func (g Group) As(target interface{}) bool {
+	for _, err := range g {
+		if As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+// +build !go1.13 go1.20
+
+package errors
+
+// Is returns whether the target is a Group with equal members. Is descends
+// into the members itself, through Unwrap.
+func (g Group) Is(target error) bool {
+	return equal(g, target)
+}
@@ -0,0 +1,286 @@
+package errors
+
+import (
+	"encoding/json"
+	"path"
+)
+
+// chainLayer is the JSON encoding of one error in a Stack.
+type chainLayer struct {
+	Message string `json:"message"`
+
+	// Summary is the message as StackString shows it, if the text of the
+	// cause in the next layer has been left out of it.
+	Summary *string `json:"summary,omitempty"`
+
+	Func string `json:"func,omitempty"`
+
+	// File is the path from FuncInfo.File(), which may reveal the
+	// directories of the build machine. It is only encoded if ModuleFile
+	// has no Path.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+
+	// ModuleFile locates the file within its module.
+	ModuleFile *ModuleFile `json:"module_file,omitempty"`
+
+	// MainModule is the main module of the process that made the layer,
+	// which StackString abbreviates as "~" in Func.
+	MainModule string `json:"main_module,omitempty"`
+
+	// Args is a pointer so that an error without ArgStringer() can be told
+	// apart from one whose args are empty.
+	Args *string `json:"args,omitempty"`
+
+	// NamedArgs holds the values of args from NewArgsBuilder.
+	NamedArgs fieldList `json:"named_args,omitempty"`
+
+	// Fields holds the key/value pairs given to FieldBuilder.With.
+	Fields fieldList `json:"fields,omitempty"`
+
+	// Group holds the encoded chains of each member of a Group.
+	Group [][]chainLayer `json:"group,omitempty"`
+
+	// Sentinel is the name under which the error was registered with
+	// RegisterSentinel.
+	Sentinel string `json:"sentinel,omitempty"`
+}
+
+// MarshalChain encodes the Stack of err as a JSON array of layers, outermost
+// first. Each layer has the error message, and if available, the func, line
+// and args reported by FuncInfo() and ArgStringer(), the ModuleFileOf the
+// FuncInfo(), the values of NamedArgs, and the fields given to
+// FieldBuilder.With as an object of typed values. Group members are encoded
+// as nested arrays of layers. Errors from other packages become message-only
+// layers. Errors from WithStack are left out. Errors registered with
+// RegisterSentinel are tagged with their name, so that UnmarshalChain can
+// restore them. Text and values are scrubbed with DefaultScrubber. The file
+// reported by FuncInfo() is left out, so that the directories of the build
+// machine are not revealed, unless the ModuleFile could not be found.
+func MarshalChain(err error) ([]byte, error) {
+	return json.Marshal(chainLayers(err))
+}
+
+// chainLayers converts each error in the Stack of err to a chainLayer.
+func chainLayers(err error) []chainLayer {
+	if err == nil {
+		return nil
+	}
+	stack := Stack(err)
+	layers := make([]chainLayer, 0, len(stack))
+	for _, err := range stack {
+		if _, ok := err.(interface{ Callers() []uintptr }); ok {
+			continue
+		}
+		layers = append(layers, chainLayerAt(err))
+	}
+	return layers
+}
+
+// chainLayerAt returns one level of chainLayers, like StackStringAt.
+func chainLayerAt(err error) chainLayer {
+	layer := chainLayer{Message: DefaultScrubber.Scrub(err.Error())}
+	if summary := DefaultScrubber.Scrub(layerMessage(err)); summary != layer.Message {
+		layer.Summary = &summary
+	}
+	if name, ok := sentinelName(err); ok {
+		layer.Sentinel = name
+		return layer
+	}
+	layer.Fields = DefaultScrubber.ScrubFields(fieldsOf(err))
+	switch err := err.(type) {
+	case Group:
+		layer.Group = make([][]chainLayer, 0, len(err))
+		for _, member := range err {
+			layer.Group = append(layer.Group, chainLayers(member))
+		}
+		return layer
+	case interface {
+		FuncInfo() FuncInfo
+	}:
+		if fi := err.FuncInfo(); fi != nil {
+			layer.Func = fi.FuncName()
+			layer.Line = fi.Line()
+			mf := ModuleFileOf(fi)
+			layer.ModuleFile = &mf
+			if mf.Path == "" {
+				layer.File = fi.File()
+			}
+			layer.MainModule = mainModuleOf(fi)
+		}
+		if as, ok := err.(interface {
+			ArgStringer() interface{ String() string }
+		}); ok {
+			argStringer := as.ArgStringer()
+			args := DefaultScrubber.Scrub(argStringer.String())
+			layer.Args = &args
+			if na, ok := argStringer.(interface{ Args() []Field }); ok {
+				layer.NamedArgs = DefaultScrubber.ScrubFields(na.Args())
+			}
+		}
+		return layer
+	case interface{ Wrapper() error }:
+		return chainLayerAt(err.Wrapper())
+	}
+	return layer
+}
+
+// MarshalJSON implements json.Marshaler using MarshalChain.
+func (w *wrapped) MarshalJSON() ([]byte, error) {
+	return MarshalChain(w)
+}
+
+// MarshalJSON implements json.Marshaler using MarshalChain.
+func (g Group) MarshalJSON() ([]byte, error) {
+	return MarshalChain(g)
+}
+
+// MarshalJSON implements json.Marshaler using MarshalChain.
+func (s *signatured) MarshalJSON() ([]byte, error) {
+	return MarshalChain(s)
+}
+
+// UnmarshalChain decodes an error chain encoded by MarshalChain. The result is
+// built from the same wrapped, Group and located errors that this package
+// produces, so that given the same Formatter settings, StackString renders it
+// like it was rendered on the encoding side. This includes the remote
+// FuncInfo values, whose main module is abbreviated as "~" and whose
+// ModuleFile is the one found by the encoding side, even if the decoding side
+// has other modules. Layers tagged as sentinels are replaced by the error
+// registered under the same name with RegisterSentinel, so that Is and As
+// keep working. Decoding null yields a nil chain.
+func UnmarshalChain(data []byte) (chain error, err error) {
+	var layers []chainLayer
+	if err := json.Unmarshal(data, &layers); err != nil {
+		return nil, err
+	}
+	return buildChain(layers), nil
+}
+
+// buildChain reverses chainLayers.
+func buildChain(layers []chainLayer) error {
+	var chain error
+	for i := len(layers) - 1; i >= 0; i-- {
+		err := buildLayer(layers[i])
+		if s, ok := err.(*signatured); ok && layers[i].Summary != nil {
+			s.short = *layers[i].Summary
+			s.cause = chain
+			chain = s.withCause()
+			continue
+		}
+		if i == len(layers)-1 {
+			chain = err
+			continue
+		}
+		chain = WrapWith(chain, err)
+	}
+	return chain
+}
+
+// buildLayer reverses chainLayerAt.
+func buildLayer(layer chainLayer) error {
+	if layer.Sentinel != "" {
+		if err := LookupSentinel(layer.Sentinel); err != nil {
+			return err
+		}
+	}
+	if layer.Group != nil {
+		g := make(Group, 0, len(layer.Group))
+		for _, member := range layer.Group {
+			g = append(g, buildChain(member))
+		}
+		return g
+	}
+	if layer.Func == "" && layer.File == "" {
+		if len(layer.Fields) != 0 {
+			return &withFields{New(layer.Message), layer.Fields}
+		}
+		return New(layer.Message)
+	}
+	fi := &remoteFuncInfo{
+		funcInfo: funcInfo{
+			file:     layer.File,
+			funcName: layer.Func,
+			line:     layer.Line,
+		},
+		mainModule: layer.MainModule,
+		moduleFile: layer.ModuleFile,
+	}
+	if layer.Args == nil {
+		return &located{message: layer.Message, fi: fi, fields: layer.Fields}
+	}
+	var argStringer interface{ String() string } = stringStringer(*layer.Args)
+	if layer.NamedArgs != nil {
+		argStringer = NamedArgs{*layer.Args, layer.NamedArgs}
+	}
+	return &signatured{
+		message:     layer.Message,
+		short:       layer.Message,
+		fi:          fi,
+		argStringer: argStringer,
+		fields:      layer.Fields,
+	}
+}
+
+// remoteFuncInfo is a FuncInfo decoded by UnmarshalChain. It remembers the
+// main module of the process that encoded it, which may differ from
+// MainModule(), and the ModuleFile that it found, since the modules of this
+// process may differ too.
+type remoteFuncInfo struct {
+	funcInfo
+	mainModule string
+	moduleFile *ModuleFile
+}
+
+// ModuleFile returns the ModuleFile found by the encoding process. Chains
+// encoded without one only give the file name.
+func (rfi *remoteFuncInfo) ModuleFile() ModuleFile {
+	if rfi.moduleFile == nil {
+		return ModuleFile{Path: path.Base(rfi.file)}
+	}
+	return *rfi.moduleFile
+}
+
+// File returns the file path sent by the encoding process. Since MarshalChain
+// leaves it out if it has the ModuleFile, the path of the file within its
+// module is returned instead, like "example.com/client/userprofile.go".
+func (rfi *remoteFuncInfo) File() string {
+	if rfi.file == "" && rfi.moduleFile != nil {
+		return path.Join(rfi.moduleFile.Module, rfi.moduleFile.Path)
+	}
+	return rfi.file
+}
+
+// remoteMainModule returns the main module of the encoding process.
+func (rfi *remoteFuncInfo) remoteMainModule() string {
+	return rfi.mainModule
+}
+
+// mainModuleOf returns the main module of the process where fi was recorded.
+func mainModuleOf(fi FuncInfo) string {
+	if rfi, ok := fi.(interface{ remoteMainModule() string }); ok {
+		return rfi.remoteMainModule()
+	}
+	return MainModule()
+}
+
+// located is a decoded error which had a FuncInfo() but no ArgStringer().
+type located struct {
+	message string
+	fi      FuncInfo
+	fields  []Field
+}
+
+func (l *located) Error() string {
+	return l.message
+}
+
+// FuncInfo returns the location of the error.
+func (l *located) FuncInfo() FuncInfo {
+	return l.fi
+}
+
+// Fields returns the key/value pairs that were encoded with the error.
+func (l *located) Fields() []Field {
+	return l.fields
+}
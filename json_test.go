@@ -0,0 +1,157 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalChain(t *testing.T) {
+	b := NewBuilder("%q", "x")
+	err := b.Wrap(fmt.Errorf("inner"), "outer")
+	line := NewFuncInfo(0).Line() - 1
+
+	buf, jsonErr := MarshalChain(err)
+	require.NoError(t, jsonErr)
+	var layers []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &layers))
+	require.Len(t, layers, 2)
+	assert.Equal(t, "outer", layers[0]["message"])
+	assert.Contains(t, layers[0]["func"], "errors.TestMarshalChain")
+	assert.NotContains(t, layers[0], "file", "build directories are not revealed")
+	mf := layers[0]["module_file"].(map[string]interface{})
+	assert.Equal(t, MainModule(), mf["module"])
+	assert.Equal(t, "json_test.go", mf["path"])
+	assert.Equal(t, float64(line), layers[0]["line"])
+	assert.Equal(t, `"x"`, layers[0]["args"])
+	assert.Equal(t, map[string]interface{}{"message": "inner"}, layers[1])
+
+	viaMarshaler, jsonErr := json.Marshal(err)
+	require.NoError(t, jsonErr)
+	assert.Equal(t, string(buf), string(viaMarshaler))
+
+	buf, jsonErr = MarshalChain(nil)
+	require.NoError(t, jsonErr)
+	assert.Equal(t, "null", string(buf))
+}
+
+func TestMarshalChainGroup(t *testing.T) {
+	g := Group{New("a"), Wrap(New("c"), "b")}
+	buf, err := json.Marshal(Wrap(g, "all"))
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`[
+			{"message": "all"},
+			{
+				"message": "[\n\ta\n\t,\n\tb\n]",
+				"group": [
+					[{"message": "a"}],
+					[{"message": "b"}, {"message": "c"}]
+				]
+			}
+		]`,
+		string(buf),
+	)
+}
+
+func TestUnmarshalChain(t *testing.T) {
+	errGone := New("gone")
+	RegisterSentinel("test.Gone", errGone)
+	defer unregisterSentinel("test.Gone")
+
+	b := NewBuilder("%q", "x")
+	orig := b.Wrap(
+		Group{
+			Wrap(errGone, "mirror a"),
+			&located{message: "mirror b", fi: NewFuncInfo(0)},
+		},
+		"fetch failed",
+	)
+	buf, err := MarshalChain(orig)
+	require.NoError(t, err)
+
+	chain, err := UnmarshalChain(buf)
+	require.NoError(t, err)
+	assert.Equal(t, orig.Error(), chain.Error())
+	assert.Equal(t, StackString(orig), StackString(chain))
+
+	reencoded, err := MarshalChain(chain)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(buf), string(reencoded))
+
+	buf, err = MarshalChain(b.Wrap(errGone, "lookup failed"))
+	require.NoError(t, err)
+	chain, err = UnmarshalChain(buf)
+	require.NoError(t, err)
+	assert.True(t, Is(chain, errGone))
+	assert.Equal(t, errGone, Unwrap(chain))
+
+	chain, err = UnmarshalChain([]byte("null"))
+	assert.NoError(t, err)
+	assert.Nil(t, chain)
+
+	_, err = UnmarshalChain([]byte("{"))
+	assert.Error(t, err)
+}
+
+func TestUnmarshalChainWrapVerb(t *testing.T) {
+	orig := NewBuilder("").Errorf("reading: %w", New("EOF"))
+	buf, err := MarshalChain(orig)
+	require.NoError(t, err)
+	chain, err := UnmarshalChain(buf)
+	require.NoError(t, err)
+	assert.Equal(t, orig.Error(), chain.Error())
+	assert.Equal(t, StackString(orig), StackString(chain))
+	assert.Equal(t, "EOF", Unwrap(chain).Error())
+}
+
+func TestUnmarshalChainFile(t *testing.T) {
+	chain, err := UnmarshalChain([]byte(`[{
+		"message": "failed",
+		"func": "example.com/m/client.F",
+		"line": 3,
+		"module_file": {"module": "example.com/m", "path": "client/x.go"}
+	}]`))
+	require.NoError(t, err)
+	fi := chain.(interface{ FuncInfo() FuncInfo }).FuncInfo()
+	assert.Equal(t, "example.com/m/client/x.go", fi.File())
+	assert.Equal(t, "example.com/m/client.F x.go:3 failed", StackString(chain))
+
+	chain, err = UnmarshalChain([]byte(`[{
+		"message": "failed",
+		"func": "example.com/m/client.F",
+		"file": "/home/bob/m/client/x.go",
+		"line": 3
+	}]`))
+	require.NoError(t, err)
+	fi = chain.(interface{ FuncInfo() FuncInfo }).FuncInfo()
+	assert.Equal(t, "/home/bob/m/client/x.go", fi.File())
+}
+
+func TestUnmarshalChainMainModule(t *testing.T) {
+	f := &Formatter{Path: ModulePath, Modules: DefaultModuleTable}
+	restore := OverrideMainModule("github.com/chaimleib/errors")
+	orig := NewBuilder("%q", "x").Wrap(New("cause"), "failed")
+	want := StackString(orig)
+	wantModulePath := f.StackString(orig)
+	buf, err := MarshalChain(orig)
+	restore()
+	require.NoError(t, err)
+	assert.Regexp(t, `^~\.TestUnmarshalChainMainModule\("x"\) `, want)
+	assert.Contains(t, wantModulePath, " ~/json_test.go:")
+
+	defer OverrideMainModule("example.com/other")()
+	chain, err := UnmarshalChain(buf)
+	require.NoError(t, err)
+	assert.Equal(t, want, StackString(chain))
+	assert.Equal(t, wantModulePath, f.StackString(chain))
+	assert.NotEqual(t, want, StackString(orig))
+
+	reencoded, err := MarshalChain(chain)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(buf), string(reencoded))
+}
@@ -0,0 +1,86 @@
+package errors
+
+// Located is an error that knows where it happened, like the errors made by
+// a Builder. Use As to get the outermost located layer of a chain:
+//
+//	var loc errors.Located
+//	if errors.As(err, &loc) {
+//		fmt.Println(loc.FuncInfo().File(), loc.FuncInfo().Line())
+//	}
+//
+// Errors made by a Builder also have an `ArgStringer() interface{ String()
+// string }` method, describing the args of the function.
+type Located interface {
+	error
+	FuncInfo() FuncInfo
+}
+
+// locatedOf returns err if it is Located, or else its Wrapper() if that is
+// Located, or nil.
+func locatedOf(err error) Located {
+	switch err := err.(type) {
+	case Located:
+		if err.FuncInfo() != nil {
+			return err
+		}
+	case interface{ Wrapper() error }:
+		return locatedOf(err.Wrapper())
+	}
+	return nil
+}
+
+// funcInfoOf returns the FuncInfo() of err or of its Wrapper(), or nil if
+// neither has one.
+func funcInfoOf(err error) FuncInfo {
+	if loc := locatedOf(err); loc != nil {
+		return loc.FuncInfo()
+	}
+	return nil
+}
+
+// Outermost returns the first located layer of err, or nil if there is none.
+// Layers are searched like As does, depth-first through Tree(err), so that
+// the located layers of Group members are found too. Layers made by Wrap are
+// represented by their Wrapper().
+func Outermost(err error) Located {
+	if locs := locatedLayers(Tree(err)); len(locs) != 0 {
+		return locs[0]
+	}
+	return nil
+}
+
+// Innermost returns the last located layer of err in the order that Outermost
+// searches them, or nil if there is none. For a chain without Groups, this is
+// the deepest one. Layers made by Wrap are represented by their Wrapper().
+func Innermost(err error) Located {
+	if locs := locatedLayers(Tree(err)); len(locs) != 0 {
+		return locs[len(locs)-1]
+	}
+	return nil
+}
+
+// locatedLayers returns the located layers of node and its descendants, from
+// the outermost.
+func locatedLayers(node *Node) []Located {
+	if node == nil {
+		return nil
+	}
+	var layers []Located
+	if loc := locatedOf(node.Err); loc != nil {
+		layers = append(layers, loc)
+	}
+	for _, child := range node.Children {
+		layers = append(layers, locatedLayers(child)...)
+	}
+	return layers
+}
+
+// Origin returns the location of Innermost(err), which is the closest known
+// place to where the error started. It returns nil if no layer has a
+// location.
+func Origin(err error) FuncInfo {
+	if loc := Innermost(err); loc != nil {
+		return loc.FuncInfo()
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func locatedTestChain() (err error, outerLine, innerLine int) {
+	inner := NewBuilder("%d", 2).Errorf("inner")
+	innerLine = NewFuncInfo(0).Line() - 1
+	middle := Wrap(inner, "middle")
+	err = NewBuilder("%d", 1).Wrap(middle, "outer")
+	outerLine = NewFuncInfo(0).Line() - 1
+	return err, outerLine, innerLine
+}
+
+func TestLocatedAs(t *testing.T) {
+	err, outerLine, _ := locatedTestChain()
+	var loc Located
+	require.True(t, As(err, &loc))
+	assert.Equal(t, "outer", loc.Error())
+	assert.Equal(t, outerLine, loc.FuncInfo().Line())
+	as, ok := loc.(interface {
+		ArgStringer() interface{ String() string }
+	})
+	require.True(t, ok)
+	assert.Equal(t, "1", as.ArgStringer().String())
+
+	var w *wrapped
+	require.True(t, As(err, &w))
+	assert.Equal(t, err, w)
+
+	assert.False(t, As(New("plain"), &loc))
+}
+
+func TestOutermostInnermost(t *testing.T) {
+	err, outerLine, innerLine := locatedTestChain()
+	assert.Equal(t, "outer", Outermost(err).Error())
+	assert.Equal(t, outerLine, Outermost(err).FuncInfo().Line())
+	assert.Equal(t, "inner", Innermost(err).Error())
+	assert.Equal(t, innerLine, Innermost(err).FuncInfo().Line())
+	assert.Equal(t, innerLine, Origin(err).Line())
+	assert.Contains(t, Origin(err).FuncName(), "locatedTestChain")
+
+	g := Wrap(Group{New("a"), err}, "group")
+	var loc Located
+	require.True(t, As(g, &loc))
+	assert.Equal(t, loc, Outermost(g))
+	assert.Equal(t, "inner", Innermost(g).Error())
+	g = Wrap(Group{nil, err, New("a")}, "group")
+	assert.Equal(t, "outer", Outermost(g).Error())
+	assert.Equal(t, innerLine, Origin(g).Line())
+	located := NewBuilder("").Errorf("located")
+	g = Wrap(Group{err, located}, "group")
+	assert.Equal(t, "outer", Outermost(g).Error())
+	assert.Equal(t, located, Innermost(g))
+
+	plain := Wrap(New("a"), "b")
+	assert.Nil(t, Outermost(plain))
+	assert.Nil(t, Innermost(plain))
+	assert.Nil(t, Origin(plain))
+	assert.Nil(t, Origin(nil))
+}
@@ -0,0 +1,132 @@
+package errors
+
+import (
+	"path"
+	"strings"
+)
+
+// ModuleFile identifies a source file by the module it belongs to, rather
+// than by where that module was on the build machine. Unlike FuncInfo.File(),
+// it is the same whether or not the binary was built with -trimpath, and it
+// does not reveal the home directories of developers.
+type ModuleFile struct {
+	// Module is the module path, like "github.com/org/repo", or "" for the
+	// standard library and other files outside of any known module.
+	Module string `json:"module,omitempty"`
+
+	// Version is the version of the module, like "v1.2.3", or "" for the
+	// main module when it was not built from a tagged version.
+	Version string `json:"version,omitempty"`
+
+	// Path is the slash-separated path of the file within Module, like
+	// "client/userprofile.go". Outside of a module, it is the import path of
+	// the package followed by the file name, like "fmt/print.go".
+	Path string `json:"path"`
+}
+
+// String joins the parts of mf like the paths of -trimpath builds, like
+// "github.com/org/repo@v1.2.3/client/userprofile.go".
+func (mf ModuleFile) String() string {
+	switch {
+	case mf.Module == "":
+		return mf.Path
+	case mf.Version == "":
+		return mf.Module + "/" + mf.Path
+	}
+	return mf.Module + "@" + mf.Version + "/" + mf.Path
+}
+
+// ModuleFileOf returns the ModuleFile of the location described by fi, using
+// its `ModuleFile() ModuleFile` method if it has one. Otherwise, the module is
+// found by matching the package of fi.FuncName() against the modules in the
+// build info of the running binary. Files of package main are placed in the
+// directory of the main package given by the build info. If that is unknown,
+// like in test binaries, Path is just the file name, unless the binary was
+// built with -trimpath.
+func ModuleFileOf(fi FuncInfo) ModuleFile {
+	switch fi := fi.(type) {
+	case nil:
+		return ModuleFile{}
+	case interface{ ModuleFile() ModuleFile }:
+		return fi.ModuleFile()
+	}
+	return newModuleFile(fi.File(), fi.FuncName())
+}
+
+// newModuleFile returns the ModuleFile of file, which contains a function
+// called funcName.
+func newModuleFile(file, funcName string) ModuleFile {
+	base := path.Base(file)
+	pkg := strings.TrimSuffix(ParseFuncName(funcName).Package, "_test")
+	if pkg == "" || pkg == "main" {
+		return mainModuleFile(file, mainPackage())
+	}
+	mod, version := moduleOf(pkg)
+	if mod == "" {
+		return ModuleFile{Path: pkg + "/" + base}
+	}
+	dir := strings.TrimPrefix(strings.TrimPrefix(pkg, mod), "/")
+	return ModuleFile{Module: mod, Version: version, Path: path.Join(dir, base)}
+}
+
+// mainModuleFile returns the ModuleFile of file in package main, whose import
+// path is mainPkg.
+func mainModuleFile(file, mainPkg string) ModuleFile {
+	base := path.Base(file)
+	mod, version := MainModule(), mainModuleVersion()
+	switch {
+	case mod == "":
+		return ModuleFile{Path: base}
+	case strings.HasPrefix(file, mod+"/"):
+		rel := strings.TrimPrefix(file, mod+"/")
+		return ModuleFile{Module: mod, Version: version, Path: rel}
+	case inModule(mainPkg, mod):
+		dir := strings.TrimPrefix(strings.TrimPrefix(mainPkg, mod), "/")
+		return ModuleFile{Module: mod, Version: version, Path: path.Join(dir, base)}
+	}
+	return ModuleFile{Module: mod, Version: version, Path: base}
+}
+
+// mainPackage returns the import path of package main in the running binary,
+// or "" if unknown. Test binaries have a generated package main, whose import
+// path ends in ".test" and is not where any source file lives.
+func mainPackage() string {
+	info := readBuildInfo()
+	if info == nil || strings.HasSuffix(info.Path, ".test") {
+		return ""
+	}
+	return info.Path
+}
+
+// moduleOf returns the path and version of the module containing the package
+// pkg, or "" if it is not in MainModule() or any dependency.
+func moduleOf(pkg string) (mod, version string) {
+	if main := MainModule(); inModule(pkg, main) {
+		mod, version = main, mainModuleVersion()
+	}
+	info := readBuildInfo()
+	if info == nil {
+		return mod, version
+	}
+	for _, dep := range info.Deps {
+		if len(dep.Path) > len(mod) && inModule(pkg, dep.Path) {
+			mod, version = dep.Path, dep.Version
+		}
+	}
+	return mod, version
+}
+
+// mainModuleVersion returns the version of MainModule(), or "" if unknown.
+func mainModuleVersion() string {
+	info := readBuildInfo()
+	if info == nil || info.Main.Path != MainModule() ||
+		info.Main.Version == "(devel)" {
+		return ""
+	}
+	return info.Main.Version
+}
+
+// inModule returns whether pkg is the module mod or one of its packages.
+func inModule(pkg, mod string) bool {
+	return mod != "" && (pkg == mod || strings.HasPrefix(pkg, mod+"/"))
+}
@@ -0,0 +1,166 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewModuleFile(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	cases := []struct {
+		file, funcName string
+		want           ModuleFile
+	}{
+		{
+			"/home/bob/src/errors/json.go",
+			"github.com/chaimleib/errors.MarshalChain",
+			ModuleFile{Module: "github.com/chaimleib/errors", Path: "json.go"},
+		},
+		{
+			"github.com/chaimleib/errors/sub/x.go",
+			"github.com/chaimleib/errors/sub.(*T).F.func1",
+			ModuleFile{Module: "github.com/chaimleib/errors", Path: "sub/x.go"},
+		},
+		{
+			"/home/bob/src/errors/x_test.go",
+			"github.com/chaimleib/errors_test.TestX",
+			ModuleFile{Module: "github.com/chaimleib/errors", Path: "x_test.go"},
+		},
+		{
+			"/usr/local/go/src/fmt/print.go",
+			"fmt.Errorf",
+			ModuleFile{Path: "fmt/print.go"},
+		},
+		{
+			"github.com/chaimleib/errors/cmd/tool/main.go",
+			"main.main",
+			ModuleFile{Module: "github.com/chaimleib/errors", Path: "cmd/tool/main.go"},
+		},
+		{
+			"/home/bob/src/errors/cmd/tool/main.go",
+			"main.main",
+			ModuleFile{Module: "github.com/chaimleib/errors", Path: "main.go"},
+		},
+	}
+	for _, c := range cases {
+		got := newModuleFile(c.file, c.funcName)
+		got.Version = ""
+		assert.Equal(t, c.want, got, c.file)
+	}
+
+	defer OverrideMainModule("")()
+	assert.Equal(t, ModuleFile{Path: "main.go"}, newModuleFile("/tmp/main.go", "main.main"))
+}
+
+func TestModuleFileDependency(t *testing.T) {
+	info := readBuildInfo()
+	if info == nil {
+		t.Skip("no build info")
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != "github.com/stretchr/testify" {
+			continue
+		}
+		assert.Equal(t, ModuleFile{
+			Module:  dep.Path,
+			Version: dep.Version,
+			Path:    "assert/assertions.go",
+		}, newModuleFile(
+			"/home/bob/go/pkg/mod/github.com/stretchr/testify@v1.4.0/assert/assertions.go",
+			"github.com/stretchr/testify/assert.Equal",
+		))
+		return
+	}
+	t.Skip("testify is not in the build info")
+}
+
+func TestModuleFileOfFrames(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	var dep FuncInfo
+	assert.Condition(t, func() bool {
+		dep = NewFuncInfo(1)
+		return true
+	})
+	require.NotNil(t, dep)
+	require.Contains(t, dep.FuncName(), "testify/assert.Condition")
+	if info := readBuildInfo(); info != nil {
+		mf := ModuleFileOf(dep)
+		assert.Equal(t, "github.com/stretchr/testify", mf.Module)
+		assert.Equal(t, "v1.4.0", mf.Version)
+		assert.Equal(t, "assert/assertions.go", mf.Path)
+	}
+
+	// The package main of a test binary is generated, so the directory of
+	// main.go is unknown.
+	main := &funcInfo{
+		file:     "/home/bob/src/errors/cmd/tool/main.go",
+		funcName: "main.main",
+		line:     1,
+	}
+	assert.Equal(t, ModuleFile{
+		Module: "github.com/chaimleib/errors",
+		Path:   "main.go",
+	}, ModuleFileOf(main))
+}
+
+func TestMainModuleFile(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	cases := []struct {
+		file, mainPkg, want string
+	}{
+		{
+			"/home/bob/src/errors/cmd/tool/main.go",
+			"github.com/chaimleib/errors/cmd/tool",
+			"cmd/tool/main.go",
+		},
+		{
+			"/home/bob/src/errors/main.go",
+			"github.com/chaimleib/errors",
+			"main.go",
+		},
+		{
+			"github.com/chaimleib/errors/cmd/tool/main.go",
+			"",
+			"cmd/tool/main.go",
+		},
+		{
+			"/home/bob/src/errors/cmd/tool/main.go",
+			"example.com/other/cmd/tool",
+			"main.go",
+		},
+	}
+	for _, c := range cases {
+		got := mainModuleFile(c.file, c.mainPkg)
+		assert.Equal(t, "github.com/chaimleib/errors", got.Module, c.file)
+		assert.Equal(t, c.want, got.Path, c.file)
+	}
+}
+
+func TestModuleFileString(t *testing.T) {
+	assert.Equal(t, "fmt/print.go", ModuleFile{Path: "fmt/print.go"}.String())
+	assert.Equal(t, "example.com/m/a/b.go", ModuleFile{Module: "example.com/m", Path: "a/b.go"}.String())
+	assert.Equal(
+		t,
+		"example.com/m@v1.2.3/a/b.go",
+		ModuleFile{Module: "example.com/m", Version: "v1.2.3", Path: "a/b.go"}.String(),
+	)
+	assert.Equal(t, ModuleFile{}, ModuleFileOf(nil))
+}
+
+func TestFormatterModulePath(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	f := &Formatter{Path: ModulePath, Modules: DefaultModuleTable}
+	err := NewBuilder("").Errorf("failed")
+	assert.Regexp(t, `\.TestFormatterModulePath\(\) ~/modulefile_test\.go:[0-9]+ failed$`, f.StackString(err))
+
+	fi := Origin(err)
+	require.NotNil(t, fi)
+	mf := ModuleFileOf(fi)
+	assert.Equal(t, "github.com/chaimleib/errors", mf.Module)
+	assert.Equal(t, "modulefile_test.go", mf.Path)
+
+	f.Modules = nil
+	assert.Regexp(t, ` github\.com/chaimleib/errors/modulefile_test\.go:[0-9]+ failed$`, f.StackString(err))
+}
@@ -1,31 +1,206 @@
 package errors
 
 import (
+	"path"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 )
 
+// buildInfo caches the result of debug.ReadBuildInfo, which parses the module
+// info embedded in the binary on every call.
+var buildInfo struct {
+	once sync.Once
+	info *debug.BuildInfo
+}
+
+// readBuildInfo returns the build info of the running binary, or nil if it
+// was not built with module support.
+func readBuildInfo() *debug.BuildInfo {
+	buildInfo.once.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			buildInfo.info = info
+		}
+	})
+	return buildInfo.info
+}
+
+// mainModuleOverride holds the value set by OverrideMainModule.
+var mainModuleOverride struct {
+	sync.RWMutex
+	mod *string
+}
+
 // MainModule returns the path of the currently-running binary's main module,
 // as defined in the go.mod file. If not built with module support, returns
-// "".
+// "". The build info is only read once.
 func MainModule() string {
-	buildInfo, ok := debug.ReadBuildInfo()
-	if !ok {
+	mainModuleOverride.RLock()
+	mod := mainModuleOverride.mod
+	mainModuleOverride.RUnlock()
+	if mod != nil {
+		return *mod
+	}
+	info := readBuildInfo()
+	if info == nil {
 		return ""
 	}
-	return buildInfo.Main.Path
+	return info.Main.Path
+}
+
+// OverrideMainModule makes MainModule return mod, until the returned function
+// is called to restore the previous value. It is meant for tests, which would
+// otherwise depend on how the test binary was built:
+//
+//	defer errors.OverrideMainModule("example.com/mymod")()
+func OverrideMainModule(mod string) (restore func()) {
+	mainModuleOverride.Lock()
+	defer mainModuleOverride.Unlock()
+	prev := mainModuleOverride.mod
+	mainModuleOverride.mod = &mod
+	return func() {
+		mainModuleOverride.Lock()
+		defer mainModuleOverride.Unlock()
+		mainModuleOverride.mod = prev
+	}
 }
 
 // RelativeModule replaces occurrences of `home` inside `modName` with a tilde
 // "~".  If `home` is the empty string or if `modName` is not a child of home,
 // just return `modName` without changing it.
 func RelativeModule(modName, home string) string {
-	if home == "" || !strings.HasPrefix(modName, home) {
-		return modName
-	}
-	postHome := modName[len(home):]
-	if postHome == "" || postHome[0] == '/' || postHome[0] == '.' {
-		return "~" + postHome
+	if abbr, ok := abbreviate(modName, home, "~"); ok {
+		return abbr
 	}
 	return modName
 }
+
+// abbreviate replaces prefix at the start of name with alias. The prefix must
+// either end with a slash, or be followed in name by a slash, a dot or
+// nothing, so that sibling modules sharing a prefix are not abbreviated.
+func abbreviate(name, prefix, alias string) (string, bool) {
+	if prefix == "" || !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	rest := name[len(prefix):]
+	if strings.HasSuffix(prefix, "/") ||
+		rest == "" || rest[0] == '/' || rest[0] == '.' {
+		return alias + rest, true
+	}
+	return "", false
+}
+
+// ModuleTable abbreviates module paths in function names, so that stack
+// strings are not dominated by long import paths. Where several prefixes
+// match, the longest one wins.
+type ModuleTable struct {
+	mu      sync.RWMutex
+	aliases map[string]string
+
+	// withMain makes the table abbreviate MainModule() as "~", unless there is
+	// an explicit alias for it.
+	withMain bool
+}
+
+// DefaultModuleTable is used by StackString. It always abbreviates the main
+// module as "~". Add to it with Set or AddBuildInfo.
+var DefaultModuleTable = &ModuleTable{withMain: true}
+
+// NewModuleTable returns an empty ModuleTable.
+func NewModuleTable() *ModuleTable {
+	return new(ModuleTable)
+}
+
+// Set makes t replace prefix by alias. A prefix like "github.com/org/" ending
+// in a slash matches anything under it, so that it could be aliased as
+// "@org/". Otherwise, the prefix matches a module and its packages, like
+// RelativeModule.
+func (t *ModuleTable) Set(prefix, alias string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.aliases == nil {
+		t.aliases = make(map[string]string)
+	}
+	t.aliases[prefix] = alias
+}
+
+// Delete removes the alias for prefix.
+func (t *ModuleTable) Delete(prefix string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.aliases, prefix)
+}
+
+// AddBuildInfo aliases dependencies of the running binary as "@" followed by
+// the last element of their module path, ignoring any major version suffix.
+// Only dependencies with a replace directive are added, which typically are
+// sibling modules in the same repository, unless allDeps is set. Dependencies
+// which already have an alias, or whose alias is taken, are skipped.
+func (t *ModuleTable) AddBuildInfo(allDeps bool) {
+	info := readBuildInfo()
+	if info == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.aliases == nil {
+		t.aliases = make(map[string]string)
+	}
+	taken := make(map[string]bool, len(t.aliases))
+	for _, alias := range t.aliases {
+		taken[alias] = true
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace == nil && !allDeps {
+			continue
+		}
+		if _, ok := t.aliases[dep.Path]; ok {
+			continue
+		}
+		alias := "@" + moduleBase(dep.Path)
+		if taken[alias] {
+			continue
+		}
+		t.aliases[dep.Path] = alias
+		taken[alias] = true
+	}
+}
+
+// Abbreviate replaces the longest matching prefix in name by its alias.
+func (t *ModuleTable) Abbreviate(name string) string {
+	return t.abbreviateWithMain(name, MainModule())
+}
+
+// abbreviateWithMain is like Abbreviate, except that main takes the place of
+// MainModule(), as for names recorded by another process.
+func (t *ModuleTable) abbreviateWithMain(name, main string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	best, bestLen := name, -1
+	if t.withMain {
+		if abbr, ok := abbreviate(name, main, "~"); ok {
+			best, bestLen = abbr, len(main)
+		}
+	}
+	for prefix, alias := range t.aliases {
+		if len(prefix) < bestLen {
+			continue
+		}
+		if abbr, ok := abbreviate(name, prefix, alias); ok {
+			best, bestLen = abbr, len(prefix)
+		}
+	}
+	return best
+}
+
+// moduleBase returns the last element of a module path, skipping a major
+// version suffix like "/v2".
+func moduleBase(mod string) string {
+	base := path.Base(mod)
+	if _, err := strconv.Atoi(strings.TrimPrefix(base, "v")); err == nil &&
+		strings.HasPrefix(base, "v") {
+		base = path.Base(path.Dir(mod))
+	}
+	return base
+}
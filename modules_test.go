@@ -14,6 +14,25 @@ func TestMainModule(t *testing.T) {
 	}
 }
 
+func TestOverrideMainModule(t *testing.T) {
+	orig := MainModule()
+	restore := OverrideMainModule("example.com/a")
+	assert.Equal(t, "example.com/a", MainModule())
+	restoreInner := OverrideMainModule("")
+	assert.Equal(t, "", MainModule())
+	restoreInner()
+	assert.Equal(t, "example.com/a", MainModule())
+	restore()
+	assert.Equal(t, orig, MainModule())
+}
+
+func BenchmarkMainModule(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MainModule()
+	}
+}
+
 func TestRelativeModule(t *testing.T) {
 	type testCase struct {
 		mod, exp string
@@ -40,3 +59,60 @@ func TestRelativeModule(t *testing.T) {
 		)
 	}
 }
+
+func TestModuleTable(t *testing.T) {
+	table := NewModuleTable()
+	table.Set("github.com/ourorg/", "@org/")
+	table.Set("github.com/ourorg/auth", "@auth")
+	table.Set("example.com/lib", "@lib")
+
+	type testCase struct {
+		name, exp string
+	}
+	cases := []testCase{
+		{"github.com/ourorg/billing.Charge", "@org/billing.Charge"},
+		{"github.com/ourorg/billing/sub.F", "@org/billing/sub.F"},
+		{"github.com/ourorg/auth.Login", "@auth.Login"},
+		{"github.com/ourorg/auth/sub.F", "@auth/sub.F"},
+		{"github.com/ourorg/authz.F", "@org/authz.F"},
+		{"example.com/lib.F", "@lib.F"},
+		{"example.com/library.F", "example.com/library.F"},
+		{"runtime.goexit", "runtime.goexit"},
+	}
+	for i, c := range cases {
+		assert.Equal(t, c.exp, table.Abbreviate(c.name), fmt.Sprintf("case %d %+v", i, c))
+	}
+
+	table.Delete("github.com/ourorg/auth")
+	assert.Equal(t, "@org/auth.Login", table.Abbreviate("github.com/ourorg/auth.Login"))
+}
+
+func TestDefaultModuleTable(t *testing.T) {
+	defer OverrideMainModule("example.com/main")()
+	assert.Equal(t, "~/sub.F", DefaultModuleTable.Abbreviate("example.com/main/sub.F"))
+
+	DefaultModuleTable.Set("example.com/main/sub", "@sub")
+	defer DefaultModuleTable.Delete("example.com/main/sub")
+	assert.Equal(t, "@sub.F", DefaultModuleTable.Abbreviate("example.com/main/sub.F"))
+	assert.Equal(t, "~/other.F", DefaultModuleTable.Abbreviate("example.com/main/other.F"))
+
+	assert.Equal(t, "example.com/main/sub.F", NewModuleTable().Abbreviate("example.com/main/sub.F"))
+}
+
+func TestModuleTableAddBuildInfo(t *testing.T) {
+	table := NewModuleTable()
+	table.AddBuildInfo(true)
+	if info := readBuildInfo(); info != nil {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/stretchr/testify" {
+				assert.Equal(t, "@testify/assert.Equal", table.Abbreviate("github.com/stretchr/testify/assert.Equal"))
+			}
+		}
+	}
+}
+
+func TestModuleBase(t *testing.T) {
+	assert.Equal(t, "testify", moduleBase("github.com/stretchr/testify"))
+	assert.Equal(t, "y", moduleBase("github.com/x/y/v2"))
+	assert.Equal(t, "yaml.v2", moduleBase("gopkg.in/yaml.v2"))
+}
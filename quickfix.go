@@ -0,0 +1,97 @@
+package errors
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Quickfix renders the located layers of err in the compiler-style format
+// read by editors' quickfix lists and problem matchers, one layer per line:
+//
+//	/abs/path/client/userprofile.go:124: ~/client.Client.Authenticate("bob"): bad password
+//
+// File paths are shown in full, or relative to Root if set. Layers without a
+// FuncInfo() are left out, and Group members are included. Path, Template,
+// Colors, Link and SourceLines do not apply.
+func (f *Formatter) Quickfix(err error) string {
+	var lines []string
+	for _, loc := range locatedLayers(Tree(err)) {
+		fi := loc.FuncInfo()
+		lines = append(lines, fmt.Sprintf(
+			"%s:%d: %s%s: %s",
+			f.rootPath(fi.File()),
+			fi.Line(),
+			f.funcNameOf(fi),
+			f.plainArgs(loc),
+			f.text(layerMessage(loc)),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GitHubAnnotations renders the located layers of err as GitHub Actions
+// workflow commands, one layer per line, which make annotations at the
+// source of each layer:
+//
+//	::error file=client/userprofile.go,line=124,title=~/client.Client.Authenticate("bob")::bad password
+//
+// Set Root to the checkout directory, like os.Getenv("GITHUB_WORKSPACE"), so
+// that paths are relative to the repository. Layers without a FuncInfo() are
+// left out, and Group members are included.
+func (f *Formatter) GitHubAnnotations(err error) string {
+	var lines []string
+	for _, loc := range locatedLayers(Tree(err)) {
+		fi := loc.FuncInfo()
+		lines = append(lines, fmt.Sprintf(
+			"::error file=%s,line=%d,title=%s::%s",
+			escapeGitHubProperty(f.rootPath(fi.File())),
+			fi.Line(),
+			escapeGitHubProperty(f.funcNameOf(fi)+f.plainArgs(loc)),
+			escapeGitHubData(f.text(layerMessage(loc))),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plainArgs returns the args of a layer in parenthesis, or "" if it has none
+// or if HideArgs is set.
+func (f *Formatter) plainArgs(err error) string {
+	as, ok := err.(interface {
+		ArgStringer() interface{ String() string }
+	})
+	if !ok || f.HideArgs {
+		return ""
+	}
+	return "(" + f.text(as.ArgStringer().String()) + ")"
+}
+
+// rootPath returns file relative to Root, or file itself if it is not inside
+// Root. Like the paths from the runtime, the result uses forward slashes.
+func (f *Formatter) rootPath(file string) string {
+	root := strings.TrimSuffix(filepath.ToSlash(f.Root), "/")
+	if root == "" || !strings.HasPrefix(file, root+"/") {
+		return file
+	}
+	return strings.TrimPrefix(file, root+"/")
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	).Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	).Replace(s)
+}
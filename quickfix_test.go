@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func quickfixTestChain() error {
+	b := NewBuilder("%q", "bob")
+	return b.Wrap(Group{
+		NewBuilder("").Errorf("50%% off: a,b\nforged"),
+		New("unlocated"),
+	}, "failed")
+}
+
+func TestFormatterQuickfix(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	lines := strings.Split(DefaultFormatter.Quickfix(quickfixTestChain()), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^/[^ ]+/quickfix_test\.go:[0-9]+: ~\.quickfixTestChain\("bob"\): failed$`, lines[0])
+	assert.Regexp(t, `^/[^ ]+/quickfix_test\.go:[0-9]+: ~\.quickfixTestChain\(\): 50% off: a,b\\nforged$`, lines[1])
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	f := &Formatter{Root: wd, HideArgs: true}
+	assert.Regexp(t, `^quickfix_test\.go:[0-9]+: [^ ]+\.quickfixTestChain: failed\n`, f.Quickfix(quickfixTestChain()))
+	assert.Equal(t, "", f.Quickfix(New("unlocated")))
+}
+
+func TestFormatterGitHubAnnotations(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	f := &Formatter{Root: wd + "/", Modules: DefaultModuleTable, Raw: true}
+	lines := strings.Split(f.GitHubAnnotations(quickfixTestChain()), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^::error file=quickfix_test\.go,line=[0-9]+,title=~\.quickfixTestChain\("bob"\)::failed$`, lines[0])
+	assert.Regexp(t, `^::error file=quickfix_test\.go,line=[0-9]+,title=~\.quickfixTestChain\(\)::50%25 off: a,b%0Aforged$`, lines[1])
+}
+
+func TestEscapeGitHub(t *testing.T) {
+	assert.Equal(t, "a%25b%0D%0Ac: d, e", escapeGitHubData("a%b\r\nc: d, e"))
+	assert.Equal(t, "a%25b%0D%0Ac%3A d%2C e", escapeGitHubProperty("a%b\r\nc: d, e"))
+}
@@ -0,0 +1,151 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedText replaces secrets wherever they would be shown.
+const redactedText = "<redacted>"
+
+// SecretValue holds a value which must never be shown. It formats as
+// "<redacted>" with every fmt verb, and encodes as "<redacted>" in JSON, so
+// it is safe to pass to builders, their args, messages and fields.
+type SecretValue struct {
+	value interface{}
+}
+
+// Secret wraps a sensitive value, like a password, so that it shows as
+// "<redacted>" in errors and their JSON encoding:
+//
+//	b := errors.NewBuilder("%q, %v", user, errors.Secret(pw))
+func Secret(v interface{}) SecretValue {
+	return SecretValue{v}
+}
+
+// Reveal returns the wrapped value.
+func (sv SecretValue) Reveal() interface{} {
+	return sv.value
+}
+
+func (sv SecretValue) String() string {
+	return redactedText
+}
+
+// GoString implements fmt.GoStringer, so that %#v does not show the value.
+func (sv SecretValue) GoString() string {
+	return redactedText
+}
+
+// Format implements fmt.Formatter, so that no verb shows the value.
+func (sv SecretValue) Format(s fmt.State, verb rune) {
+	io.WriteString(s, redactedText)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (sv SecretValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redactedText + `"`), nil
+}
+
+// Scrubber removes sensitive text from rendered errors. It replaces matches of
+// its patterns, and the values after its secret keys, like the "hunter2" in
+// `password="hunter2"`, with "<redacted>". Fields and named args whose names
+// are secret keys are redacted too. The zero value scrubs nothing.
+type Scrubber struct {
+	mu         sync.RWMutex
+	patterns   []*regexp.Regexp
+	keys       map[string]bool
+	keyPattern *regexp.Regexp
+}
+
+// DefaultScrubber is used by the Formatters without a Scrubber, including
+// DefaultFormatter, by MarshalChain, and by the %#v verb of the errors of this
+// package.
+var DefaultScrubber = new(Scrubber)
+
+// RegisterScrubPattern adds a pattern to DefaultScrubber.
+func RegisterScrubPattern(re *regexp.Regexp) {
+	DefaultScrubber.AddPattern(re)
+}
+
+// RegisterSecretKey adds a secret key to DefaultScrubber.
+func RegisterSecretKey(key string) {
+	DefaultScrubber.AddKey(key)
+}
+
+// AddPattern makes s replace every match of re with "<redacted>".
+func (s *Scrubber) AddPattern(re *regexp.Regexp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patterns = append(s.patterns, re)
+}
+
+// AddKey makes s redact fields and named args called key, and the values
+// following key and an "=" or ":" in text. Keys are not case-sensitive.
+func (s *Scrubber) AddKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[string]bool)
+	}
+	s.keys[strings.ToLower(key)] = true
+	quoted := make([]string, 0, len(s.keys))
+	for key := range s.keys {
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+	sort.Strings(quoted)
+	s.keyPattern = regexp.MustCompile(
+		`(?i)(\b(?:` + strings.Join(quoted, "|") + `)\b["']?\s*[=:]\s*)` +
+			`("[^"]*"|'[^']*'|[^\s,;)}\]]+)`,
+	)
+}
+
+// IsSecretKey returns whether key was added with AddKey.
+func (s *Scrubber) IsSecretKey(key string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[strings.ToLower(key)]
+}
+
+// Scrub returns text with its sensitive parts replaced by "<redacted>".
+func (s *Scrubber) Scrub(text string) string {
+	if s == nil {
+		return text
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.keyPattern != nil {
+		text = s.keyPattern.ReplaceAllString(text, "${1}"+redactedText)
+	}
+	for _, re := range s.patterns {
+		text = re.ReplaceAllString(text, redactedText)
+	}
+	return text
+}
+
+// ScrubFields returns a copy of fields, where the values of secret keys are
+// Redacted, and string values are scrubbed.
+func (s *Scrubber) ScrubFields(fields []Field) []Field {
+	if s == nil || fields == nil {
+		return fields
+	}
+	scrubbed := make([]Field, len(fields))
+	for i, field := range fields {
+		switch value := field.Value.(type) {
+		case string:
+			field.Value = s.Scrub(value)
+		}
+		if s.IsSecretKey(field.Key) {
+			field.Value = Redacted
+		}
+		scrubbed[i] = field
+	}
+	return scrubbed
+}
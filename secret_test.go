@@ -0,0 +1,153 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecret(t *testing.T) {
+	s := Secret("hunter2")
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q", "%d", "%x"} {
+		assert.Equal(t, "<redacted>", fmt.Sprintf(verb, s), verb)
+	}
+	assert.Equal(t, "hunter2", s.Reveal())
+
+	buf, err := json.Marshal(map[string]interface{}{"pw": s})
+	require.NoError(t, err)
+	var decoded map[string]string
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	assert.Equal(t, map[string]string{"pw": "<redacted>"}, decoded)
+
+	b := NewBuilder("%q, %v", "bob", s)
+	chain := b.With("token", Secret("abc")).Errorf("login failed with %v", s)
+	assert.Regexp(
+		t,
+		`\.TestSecret\("bob", <redacted>\) secret_test\.go:[0-9]+ login failed with <redacted> \{token=<redacted>\}$`,
+		StackString(chain),
+	)
+	buf, err = MarshalChain(chain)
+	require.NoError(t, err)
+	assert.NotContains(t, string(buf), "hunter2")
+	assert.NotContains(t, string(buf), "abc")
+}
+
+func TestScrubber(t *testing.T) {
+	s := new(Scrubber)
+	assert.Equal(t, "password=hunter2", s.Scrub("password=hunter2"))
+
+	s.AddKey("Password")
+	s.AddPattern(regexp.MustCompile(`sk_[a-z0-9]+`))
+	assert.True(t, s.IsSecretKey("PASSWORD"))
+	assert.False(t, s.IsSecretKey("user"))
+	assert.Equal(t, "password=<redacted>, user=bob", s.Scrub("password=hunter2, user=bob"))
+	assert.Equal(t, `(password=<redacted>)`, s.Scrub(`(password="hunter 2")`))
+	assert.Equal(t, `{"Password": <redacted>}`, s.Scrub(`{"Password": "x"}`))
+	assert.Equal(t, "key <redacted> rejected", s.Scrub("key sk_live42 rejected"))
+	assert.Equal(t, "passwords are hard", s.Scrub("passwords are hard"))
+
+	assert.Equal(t, []Field{
+		{"password", Redacted},
+		{"note", "uses <redacted>"},
+		{"n", 1},
+	}, s.ScrubFields([]Field{
+		{"password", "hunter2"},
+		{"note", "uses sk_abc"},
+		{"n", 1},
+	}))
+
+	var nilScrubber *Scrubber
+	assert.Equal(t, "password=x", nilScrubber.Scrub("password=x"))
+}
+
+func TestFormatterScrubber(t *testing.T) {
+	s := new(Scrubber)
+	s.AddKey("pw")
+	f := &Formatter{Scrubber: s}
+	b := NewArgsBuilder("user", "bob", "pw", "hunter2")
+	err := b.With("pw", "hunter2").Errorf("bad login pw=hunter2")
+	out := f.StackString(err)
+	assert.Regexp(
+		t,
+		`\(user="bob", pw=<redacted>\) secret_test\.go:[0-9]+ bad login pw=<redacted> \{pw=<redacted>\}$`,
+		out,
+	)
+	assert.NotContains(t, out, "hunter2")
+}
+
+func TestMarshalChainScrubber(t *testing.T) {
+	defer func(s *Scrubber) { DefaultScrubber = s }(DefaultScrubber)
+	DefaultScrubber = new(Scrubber)
+	DefaultScrubber.AddKey("pw")
+
+	b := NewArgsBuilder("user", "bob", "pw", "hunter2")
+	err := b.With("pw", "hunter2").Errorf("bad login pw=hunter2")
+	buf, jsonErr := MarshalChain(err)
+	require.NoError(t, jsonErr)
+	assert.NotContains(t, string(buf), "hunter2")
+	var layers []struct {
+		NamedArgs map[string]string `json:"named_args"`
+	}
+	require.NoError(t, json.Unmarshal(buf, &layers))
+	require.Len(t, layers, 1)
+	assert.Equal(t, map[string]string{"user": "bob", "pw": "<redacted>"}, layers[0].NamedArgs)
+}
+
+func TestGoStringScrubber(t *testing.T) {
+	defer func(s *Scrubber) { DefaultScrubber = s }(DefaultScrubber)
+	DefaultScrubber = new(Scrubber)
+	DefaultScrubber.AddKey("pw")
+	DefaultScrubber.AddPattern(regexp.MustCompile(`sk_[a-z0-9]+`))
+
+	b := NewArgsBuilder("user", "bob", "pw", "hunter2")
+	err := b.Wrap(New("cause"), "bad login pw=hunter2 with sk_abc")
+	out := fmt.Sprintf("%#v", err)
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "sk_abc")
+	assert.Contains(t, out, `message:"bad login pw=<redacted> with <redacted>"`)
+	assert.Contains(t, out, `argStringer:"user=\"bob\", pw=<redacted>"`)
+}
+
+func TestGoStringScrubberWrapped(t *testing.T) {
+	defer func(s *Scrubber) { DefaultScrubber = s }(DefaultScrubber)
+	DefaultScrubber = new(Scrubber)
+	RegisterSecretKey("password")
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"Wrap", Wrap(New("password=hunter2"), "login password=hunter2")},
+		{"WithStack", WithStack(New("password=hunter2"))},
+		{"WithFields", WithFields(BuiltinBuilder, "password", "hunter2").
+			Wrap(New("password=hunter2"), "login")},
+		{"cause", NewBuilder("login").Wrap(New("password=hunter2"), "failed")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := fmt.Sprintf("%#v", c.err)
+			assert.NotContains(t, out, "hunter2")
+			assert.Contains(t, out, "<redacted>")
+		})
+	}
+	assert.Equal(
+		t,
+		`&errors.wrapped{error:*errors.errorString("login password=<redacted>"), `+
+			`wrapped:*errors.errorString("password=<redacted>")}`,
+		fmt.Sprintf("%#v", cases[0].err),
+	)
+}
+
+func TestDefaultFormatterScrubber(t *testing.T) {
+	defer func(s *Scrubber) { DefaultScrubber = s }(DefaultScrubber)
+	DefaultScrubber = new(Scrubber)
+	DefaultScrubber.AddKey("pw")
+
+	err := New("bad login pw=hunter2")
+	assert.Equal(t, "bad login pw=<redacted>", StackString(err))
+	assert.Equal(t, "bad login pw=hunter2", (&Formatter{Scrubber: new(Scrubber)}).StackString(err))
+}
@@ -0,0 +1,70 @@
+package errors
+
+import (
+	"sync"
+)
+
+// sentinels holds the errors registered with RegisterSentinel, in
+// registration order.
+var sentinels struct {
+	sync.RWMutex
+	names []string
+	errs  map[string]error
+}
+
+// RegisterSentinel makes a well-known error value recognizable across
+// processes. MarshalChain tags the error with name, and UnmarshalChain
+// replaces layers with that tag by err, so that Is(decoded, err) holds on the
+// decoding side. Both sides must register the same names. Registering a name
+// again replaces the previous error.
+func RegisterSentinel(name string, err error) {
+	if name == "" || err == nil {
+		panic("errors: sentinel name and error must be non-empty")
+	}
+	sentinels.Lock()
+	defer sentinels.Unlock()
+	if sentinels.errs == nil {
+		sentinels.errs = make(map[string]error)
+	}
+	if _, ok := sentinels.errs[name]; !ok {
+		sentinels.names = append(sentinels.names, name)
+	}
+	sentinels.errs[name] = err
+}
+
+// unregisterSentinel forgets the error registered under name, so that tests
+// leave the registry like they found it.
+func unregisterSentinel(name string) {
+	sentinels.Lock()
+	defer sentinels.Unlock()
+	if _, ok := sentinels.errs[name]; !ok {
+		return
+	}
+	delete(sentinels.errs, name)
+	for i, n := range sentinels.names {
+		if n == name {
+			sentinels.names = append(sentinels.names[:i], sentinels.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// LookupSentinel returns the error registered under name, or nil if there is
+// none.
+func LookupSentinel(name string) error {
+	sentinels.RLock()
+	defer sentinels.RUnlock()
+	return sentinels.errs[name]
+}
+
+// sentinelName returns the name under which err was registered.
+func sentinelName(err error) (string, bool) {
+	sentinels.RLock()
+	defer sentinels.RUnlock()
+	for _, name := range sentinels.names {
+		if equal(sentinels.errs[name], err) {
+			return name, true
+		}
+	}
+	return "", false
+}
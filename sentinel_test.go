@@ -0,0 +1,51 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterSentinel(t *testing.T) {
+	errNotFound := New("not found")
+	RegisterSentinel("test.NotFound", errNotFound)
+	defer unregisterSentinel("test.NotFound")
+	assert.Equal(t, errNotFound, LookupSentinel("test.NotFound"))
+	assert.Nil(t, LookupSentinel("test.Missing"))
+
+	name, ok := sentinelName(errNotFound)
+	assert.True(t, ok)
+	assert.Equal(t, "test.NotFound", name)
+	_, ok = sentinelName(New("not found"))
+	assert.False(t, ok)
+	_, ok = sentinelName(Group{errNotFound})
+	assert.False(t, ok)
+
+	assert.Panics(t, func() { RegisterSentinel("", errNotFound) })
+	assert.Panics(t, func() { RegisterSentinel("test.Nil", nil) })
+
+	unregisterSentinel("test.NotFound")
+	assert.Nil(t, LookupSentinel("test.NotFound"))
+	_, ok = sentinelName(errNotFound)
+	assert.False(t, ok)
+}
+
+func TestEqual(t *testing.T) {
+	a := New("a")
+	assert.True(t, equal(a, a))
+	assert.False(t, equal(a, New("a")))
+	assert.True(t, equal(nil, nil))
+	assert.False(t, equal(a, nil))
+	b := New("b")
+	assert.True(t, equal(WrapWith(a, b), WrapWith(a, b)))
+	assert.False(t, equal(Wrap(a, "b"), Wrap(a, "b")))
+	assert.True(t, equal(Group{a}, Group{a}))
+	assert.False(t, equal(Group{a}, Group{a, a}))
+
+	uncomparable := &multiError{"m", nil}
+	assert.False(t, equal(*uncomparable, *uncomparable))
+	type holder struct{ err error }
+	assert.False(t, equal(holder{Group{a}}, holder{Group{a}}))
+	assert.True(t, equal(holder{a}, holder{a}))
+	assert.True(t, equal(holder{}, holder{}))
+}
@@ -0,0 +1,193 @@
+package errors
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SourceReader reads source files for Formatter snippets, given the path from
+// FuncInfo.File(). Implement it to read sources from somewhere other than the
+// disk, like files embedded in the binary.
+type SourceReader interface {
+	ReadSource(path string) ([]byte, error)
+}
+
+// SourceReaderFunc adapts a function to a SourceReader.
+type SourceReaderFunc func(path string) ([]byte, error)
+
+// ReadSource calls srf(path).
+func (srf SourceReaderFunc) ReadSource(path string) ([]byte, error) {
+	return srf(path)
+}
+
+// DiskSource reads source files from the disk.
+var DiskSource SourceReader = SourceReaderFunc(ioutil.ReadFile)
+
+// maxSourceFiles is the number of files that a SourceCache remembers.
+const maxSourceFiles = 64
+
+// SourceCache remembers the files read by another SourceReader, including
+// those which could not be read; call Reset to try them again. Once it holds
+// 64 files, it forgets the least recently used. It is safe to use from several
+// goroutines.
+type SourceCache struct {
+	reader SourceReader
+	mu     sync.Mutex
+	files  map[string]*list.Element
+	recent *list.List // of *sourceFile, most recently used first
+}
+
+// sourceFile is an entry of a SourceCache. once guards the fields after it,
+// so that slow reads do not block reads of other files.
+type sourceFile struct {
+	path  string
+	once  sync.Once
+	buf   []byte
+	lines []string
+	err   error
+}
+
+// DefaultSourceCache caches DiskSource. Formatters use it when their Source is
+// nil.
+var DefaultSourceCache = NewSourceCache(DiskSource)
+
+// NewSourceCache returns a SourceCache for reader.
+func NewSourceCache(reader SourceReader) *SourceCache {
+	return &SourceCache{
+		reader: reader,
+		files:  make(map[string]*list.Element),
+		recent: list.New(),
+	}
+}
+
+// ReadSource returns the contents of path, reading it only the first time.
+func (sc *SourceCache) ReadSource(path string) ([]byte, error) {
+	file := sc.file(path)
+	return file.buf, file.err
+}
+
+// lines returns the lines of path, reading it only the first time.
+func (sc *SourceCache) lines(path string) ([]string, error) {
+	file := sc.file(path)
+	return file.lines, file.err
+}
+
+// Reset forgets all files, so that changes to them are seen, and files which
+// could not be read are tried again.
+func (sc *SourceCache) Reset() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.files = make(map[string]*list.Element)
+	sc.recent.Init()
+}
+
+// file returns the entry for path, after reading it if needed.
+func (sc *SourceCache) file(path string) *sourceFile {
+	sc.mu.Lock()
+	elem, ok := sc.files[path]
+	if ok {
+		sc.recent.MoveToFront(elem)
+	} else {
+		if sc.recent.Len() >= maxSourceFiles {
+			oldest := sc.recent.Remove(sc.recent.Back()).(*sourceFile)
+			delete(sc.files, oldest.path)
+		}
+		elem = sc.recent.PushFront(&sourceFile{path: path})
+		sc.files[path] = elem
+	}
+	file := elem.Value.(*sourceFile)
+	sc.mu.Unlock()
+	file.once.Do(func() {
+		file.buf, file.err = sc.reader.ReadSource(path)
+		if file.err == nil {
+			file.lines = splitLines(file.buf)
+		}
+	})
+	return file
+}
+
+// splitLines splits buf into lines without their line endings.
+func splitLines(buf []byte) []string {
+	buf = bytes.TrimSuffix(buf, []byte("\n"))
+	lines := strings.Split(string(buf), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// snippet returns the SourceLines around the location of fi, each line after
+// a separator. The failing line is marked with ">". If the file may not or
+// cannot be read, or the line is not in it, snippet returns "".
+func (f *Formatter) snippet(fi FuncInfo) string {
+	file, line := fi.File(), fi.Line()
+	if f.SourceLines <= 0 || file == "" || line <= 0 || !f.mayRead(fi) {
+		return ""
+	}
+	source := f.Source
+	if source == nil {
+		source = DefaultSourceCache
+	}
+	var lines []string
+	if sc, ok := source.(*SourceCache); ok {
+		var err error
+		if lines, err = sc.lines(file); err != nil {
+			return ""
+		}
+	} else {
+		buf, err := source.ReadSource(file)
+		if err != nil {
+			return ""
+		}
+		lines = splitLines(buf)
+	}
+	if line > len(lines) {
+		return ""
+	}
+	first, last := line-f.SourceLines, line+f.SourceLines
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		mark := " "
+		if n == line {
+			mark = ">"
+		}
+		text := strings.Replace(lines[n-1], "\t", "    ", -1)
+		fmt.Fprintf(
+			&b,
+			"%s\t%s %*d | %s",
+			f.separator(),
+			mark,
+			width,
+			n,
+			f.escape(text),
+		)
+	}
+	return b.String()
+}
+
+// mayRead returns whether the source of fi may be shown. Paths decoded by
+// UnmarshalChain were chosen by the encoding side, so they could point to any
+// file on this machine. Other paths must be inside Root, or else the package
+// of fi must be in the main module.
+func (f *Formatter) mayRead(fi FuncInfo) bool {
+	if _, ok := fi.(interface{ remoteMainModule() string }); ok {
+		return false
+	}
+	if f.Root != "" {
+		return f.rootPath(fi.File()) != fi.File()
+	}
+	main := MainModule()
+	return main != "" && ModuleFileOf(fi).Module == main
+}
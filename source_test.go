@@ -0,0 +1,137 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatterSourceLines(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	f := &Formatter{SourceLines: 1}
+	b := NewBuilder("")
+	err := b.Errorf("failed") // the failing line
+	lines := strings.Split(f.StackString(err), "\n")
+	require.Len(t, lines, 4)
+	assert.Regexp(t, `\.TestFormatterSourceLines\(\) source_test\.go:[0-9]+ failed$`, lines[0])
+	assert.Regexp(t, `^\t  [0-9]+ \|     b := NewBuilder\(""\)$`, lines[1])
+	assert.Regexp(t, `^\t> [0-9]+ \|     err := b\.Errorf\("failed"\) // the failing line$`, lines[2])
+	assert.Regexp(t, `^\t  [0-9]+ \|     lines := `, lines[3])
+}
+
+func TestFormatterSourceMissing(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	f := &Formatter{
+		SourceLines: 2,
+		Source: SourceReaderFunc(func(path string) ([]byte, error) {
+			return nil, fmt.Errorf("no sources")
+		}),
+	}
+	b := NewBuilder("")
+	err := b.Errorf("failed")
+	assert.Regexp(t, `^[^\n]+ failed$`, f.StackString(err))
+	assert.Equal(t, "", f.snippet(&funcInfo{line: 1}))
+}
+
+func TestFormatterSourceReader(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	f := &Formatter{
+		SourceLines: 1,
+		Source: SourceReaderFunc(func(path string) ([]byte, error) {
+			return []byte("one\r\n\ttwo\r\nthree\x1b[31m\r\n"), nil
+		}),
+	}
+	at := func(line int) FuncInfo {
+		return &funcInfo{
+			file:     "/src/errors/x.go",
+			funcName: "github.com/chaimleib/errors.F",
+			line:     line,
+		}
+	}
+	assert.Equal(t, "\n\t  1 | one\n\t> 2 |     two\n\t  3 | three\\x1b[31m", f.snippet(at(2)))
+	assert.Equal(t, "\n\t> 1 | one\n\t  2 |     two", f.snippet(at(1)))
+	assert.Equal(t, "", f.snippet(at(4)))
+}
+
+func TestFormatterSourceAllowed(t *testing.T) {
+	defer OverrideMainModule("github.com/chaimleib/errors")()
+	var read []string
+	f := &Formatter{
+		SourceLines: 1,
+		Source: SourceReaderFunc(func(path string) ([]byte, error) {
+			read = append(read, path)
+			return []byte("secret\n"), nil
+		}),
+	}
+
+	chain, err := UnmarshalChain([]byte(`[{
+		"message": "failed",
+		"func": "github.com/chaimleib/errors.F",
+		"file": "/etc/passwd",
+		"line": 1,
+		"args": ""
+	}]`))
+	require.NoError(t, err)
+	assert.NotContains(t, f.StackString(chain), "secret")
+
+	other := &funcInfo{file: "/src/other/x.go", funcName: "example.com/other.F", line: 1}
+	assert.Equal(t, "", f.snippet(other))
+	assert.Empty(t, read)
+
+	f.Root = "/src/other/"
+	assert.Contains(t, f.snippet(other), "secret")
+	mine := &funcInfo{file: "/src/errors/x.go", funcName: "github.com/chaimleib/errors.F", line: 1}
+	assert.Equal(t, "", f.snippet(mine))
+	assert.Equal(t, []string{"/src/other/x.go"}, read)
+}
+
+func TestSourceCache(t *testing.T) {
+	var mu sync.Mutex
+	reads := make(map[string]int)
+	sc := NewSourceCache(SourceReaderFunc(func(path string) ([]byte, error) {
+		mu.Lock()
+		reads[path]++
+		mu.Unlock()
+		if path == "missing.go" {
+			return nil, fmt.Errorf("not found")
+		}
+		return []byte("package " + path + "\n"), nil
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf, err := sc.ReadSource("a")
+			assert.NoError(t, err)
+			assert.Equal(t, "package a\n", string(buf))
+			_, err = sc.ReadSource("missing.go")
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, reads["a"])
+	assert.Equal(t, 1, reads["missing.go"], "errors are cached")
+
+	for i := 0; i < maxSourceFiles; i++ {
+		_, err := sc.ReadSource(fmt.Sprint("f", i))
+		require.NoError(t, err)
+		sc.ReadSource("a")
+	}
+	sc.ReadSource("f1")
+	assert.Equal(t, 1, reads["f1"])
+	assert.Equal(t, 1, reads["a"], "recently used files are kept")
+	sc.ReadSource("f0")
+	assert.Equal(t, 2, reads["f0"], "the least recently used file is forgotten")
+	sc.ReadSource("missing.go")
+	assert.Equal(t, 2, reads["missing.go"])
+
+	sc.Reset()
+	sc.ReadSource("a")
+	assert.Equal(t, 2, reads["a"])
+}
@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileLink is a Formatter.Link template which opens source files in the
+// default application.
+const FileLink = "file://{file}"
+
+// ColorScheme holds the ANSI SGR parameters used to highlight each part of a
+// layer, like "1" for bold or "36" for cyan. Empty parts are not highlighted.
+type ColorScheme struct {
+	Func     string
+	Args     string
+	Location string
+	Message  string
+	Fields   string
+}
+
+// DefaultColorScheme is the ColorScheme of NewTerminalFormatter.
+var DefaultColorScheme = ColorScheme{
+	Func:     "1",
+	Args:     "36",
+	Location: "2",
+	Message:  "31",
+	Fields:   "33",
+}
+
+// NewTerminalFormatter returns a copy of DefaultFormatter for writing to w,
+// which uses DefaultColorScheme if w is a terminal. Colors are left off if
+// the NO_COLOR environment variable is set, or if TERM is "dumb".
+func NewTerminalFormatter(w io.Writer) *Formatter {
+	f := *DefaultFormatter
+	if IsTerminal(w) &&
+		os.Getenv("NO_COLOR") == "" &&
+		os.Getenv("TERM") != "dumb" {
+		colors := DefaultColorScheme
+		f.Colors = &colors
+	}
+	return &f
+}
+
+// IsTerminal returns whether w is an *os.File connected to a terminal. Other
+// character devices, like /dev/null, are not terminals. On systems other than
+// Linux, the BSDs, macOS and Windows, it always returns false.
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isTerminal(file.Fd())
+}
+
+func (f *Formatter) colors() ColorScheme {
+	if f.Colors == nil {
+		return ColorScheme{}
+	}
+	return *f.Colors
+}
+
+// paint highlights text with the given SGR parameters.
+func paint(sgr, text string) string {
+	if sgr == "" || text == "" {
+		return text
+	}
+	return "\x1b[" + sgr + "m" + text + "\x1b[0m"
+}
+
+// link wraps text in an OSC 8 hyperlink to file and line, following the Link
+// template.
+func (f *Formatter) link(file string, line int, text string) string {
+	if f.Link == "" || file == "" {
+		return text
+	}
+	target := strings.NewReplacer(
+		"{file}", (&url.URL{Path: file}).EscapedPath(),
+		"{line}", strconv.Itoa(line),
+	).Replace(f.Link)
+	return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
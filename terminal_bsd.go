@@ -0,0 +1,21 @@
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package errors
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal returns whether fd is a terminal, which is when it has terminal
+// attributes.
+func isTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		fd,
+		syscall.TIOCGETA,
+		uintptr(unsafe.Pointer(&termios)),
+	)
+	return errno == 0
+}
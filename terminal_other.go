@@ -0,0 +1,8 @@
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package errors
+
+// isTerminal is not implemented on this system.
+func isTerminal(fd uintptr) bool {
+	return false
+}
@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatterColors(t *testing.T) {
+	f := &Formatter{Colors: &DefaultColorScheme}
+	b := NewBuilder("%q", "x")
+	err := b.With("k", 1).Wrap(New("cause"), "failed")
+	assert.Regexp(
+		t,
+		`^\x1b\[1m[^ ]+\.TestFormatterColors\x1b\[0m`+
+			`\x1b\[36m\("x"\)\x1b\[0m `+
+			`\x1b\[2mterminal_test\.go:[0-9]+\x1b\[0m `+
+			`\x1b\[31mfailed\x1b\[0m `+
+			`\x1b\[33m\{k=1\}\x1b\[0m\n`+
+			`\x1b\[31mcause\x1b\[0m$`,
+		f.StackString(err),
+	)
+}
+
+func TestFormatterLink(t *testing.T) {
+	f := &Formatter{Link: "vscode://file{file}:{line}"}
+	b := NewBuilder("")
+	err := b.Errorf("failed")
+	fi := Origin(err)
+	require.NotNil(t, fi)
+	assert.Regexp(
+		t,
+		`^[^ ]+\.TestFormatterLink\(\) `+
+			`\x1b\]8;;vscode://file/[^ ]*/terminal_test\.go:[0-9]+\x1b\\`+
+			`terminal_test\.go:[0-9]+`+
+			`\x1b\]8;;\x1b\\ failed$`,
+		f.StackString(err),
+	)
+	assert.Equal(
+		t,
+		"\x1b]8;;file:///a%20b/c.go\x1b\\c.go:3\x1b]8;;\x1b\\",
+		(&Formatter{Link: FileLink}).link("/a b/c.go", 3, "c.go:3"),
+	)
+}
+
+func TestNewTerminalFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewTerminalFormatter(&buf)
+	assert.Nil(t, f.Colors)
+	assert.Equal(t, DefaultFormatter.Modules, f.Modules)
+	assert.Equal(t, DefaultFormatter.Scrubber, f.Scrubber)
+	assert.False(t, IsTerminal(&buf))
+
+	file, err := ioutil.TempFile("", "errors")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+	assert.False(t, IsTerminal(file))
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer devNull.Close()
+	assert.False(t, IsTerminal(devNull))
+}
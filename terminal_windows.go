@@ -0,0 +1,11 @@
+// +build windows
+
+package errors
+
+import "syscall"
+
+// isTerminal returns whether fd is a console.
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}
@@ -0,0 +1,98 @@
+package errors
+
+// Node is an error in a Tree, with the errors it wraps as its Children.
+type Node struct {
+	Err      error
+	Children []*Node
+}
+
+// Tree returns the tree of errors found by recursively unwrapping err. Unlike
+// Stack, it descends into every member of a Group and of errors with an
+// `Unwrap() []error` method, like those that Go 1.20 and later make for
+// several %w verbs. Errors with an `Unwrap() error` method, or else a
+// `Cause() error` method like those of github.com/pkg/errors, have their
+// cause as their only child. Nil causes and members are left out. Tree returns
+// nil if err is nil.
+func Tree(err error) *Node {
+	if err == nil {
+		return nil
+	}
+	node := &Node{Err: err}
+	for _, cause := range causes(err) {
+		if child := Tree(cause); child != nil {
+			node.Children = append(node.Children, child)
+		}
+	}
+	return node
+}
+
+// causes returns the errors directly wrapped by err.
+func causes(err error) []error {
+	switch err := err.(type) {
+	case Group:
+		return err
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Unwrap() error }:
+		if cause := err.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	case interface{ Cause() error }:
+		if cause := err.Cause(); cause != nil {
+			return []error{cause}
+		}
+	}
+	return nil
+}
+
+// Cause returns the innermost error of err, found by following Unwrap()
+// methods, or else Cause() methods like those of github.com/pkg/errors. Of
+// the members of a Group or of an error with an `Unwrap() []error` method,
+// the first non-nil one is followed. Cause returns err itself if it wraps
+// nothing, and nil if err is nil.
+func Cause(err error) error {
+	path := causePath(err)
+	if len(path) == 0 {
+		return nil
+	}
+	return path[len(path)-1]
+}
+
+// RootCauses returns every innermost error of err, which are the leaves of
+// Tree(err), in order. For a linear chain, it returns only Cause(err).
+func RootCauses(err error) []error {
+	return leaves(Tree(err))
+}
+
+// leaves returns the errors of the nodes under node without children.
+func leaves(node *Node) []error {
+	if node == nil {
+		return nil
+	}
+	if len(node.Children) == 0 {
+		return []error{node.Err}
+	}
+	var errs []error
+	for _, child := range node.Children {
+		errs = append(errs, leaves(child)...)
+	}
+	return errs
+}
+
+// causePath returns err and the errors that Cause follows from it, outermost
+// first.
+func causePath(err error) []error {
+	var path []error
+	for err != nil {
+		path = append(path, err)
+		var next error
+		for _, cause := range causes(err) {
+			if cause != nil {
+				next = cause
+				break
+			}
+		}
+		err = next
+	}
+	return path
+}
@@ -0,0 +1,154 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// multiError imitates the errors made by Go 1.20 for several %w verbs.
+type multiError struct {
+	msg  string
+	errs []error
+}
+
+func (me *multiError) Error() string   { return me.msg }
+func (me *multiError) Unwrap() []error { return me.errs }
+
+// causeError imitates the errors of github.com/pkg/errors before v0.9, which
+// only have a Cause() method.
+type causeError struct {
+	msg   string
+	cause error
+}
+
+func (ce *causeError) Error() string { return ce.msg + ": " + ce.cause.Error() }
+func (ce *causeError) Cause() error  { return ce.cause }
+
+// pkgErrorsCause is a copy of Cause from github.com/pkg/errors.
+func pkgErrorsCause(err error) error {
+	type causer interface {
+		Cause() error
+	}
+	for err != nil {
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return err
+}
+
+func TestTree(t *testing.T) {
+	assert.Nil(t, Tree(nil))
+
+	a, c, d := New("a"), New("c"), New("d")
+	b := Wrap(c, "b")
+	multi := &multiError{"multi", []error{d, nil}}
+	g := Group{a, b, multi}
+	outer := Wrap(g, "outer")
+
+	assert.Equal(t, &Node{
+		Err: outer,
+		Children: []*Node{{
+			Err: g,
+			Children: []*Node{
+				{Err: a},
+				{Err: b, Children: []*Node{{Err: c}}},
+				{Err: multi, Children: []*Node{{Err: d}}},
+			},
+		}},
+	}, Tree(outer))
+}
+
+func TestStackStringTree(t *testing.T) {
+	g := Group{
+		Wrap(New("a2"), "a1"),
+		&multiError{"b1", []error{
+			New("b2"),
+			Wrap(New("b4"), "b3"),
+		}},
+		New("c1\nc1 continued"),
+	}
+	assert.Equal(
+		t,
+		"outer\n"+
+			"├─ a1\n"+
+			"│  a2\n"+
+			"├─ b1\n"+
+			"│  ├─ b2\n"+
+			"│  └─ b3\n"+
+			"│     b4\n"+
+			"└─ c1\\nc1 continued",
+		StackString(Wrap(g, "outer")),
+	)
+
+	f := &Formatter{Branches: ASCIITreeStyle, MaxDepth: 2, Raw: true}
+	assert.Equal(
+		t,
+		"outer\n"+
+			"|- a1\n"+
+			"|  ... (1 more layer)\n"+
+			"|- b1\n"+
+			"|  ... (3 more layers)\n"+
+			"`- c1\n"+
+			"   c1 continued",
+		f.StackString(Wrap(g, "outer")),
+	)
+}
+
+func TestCause(t *testing.T) {
+	assert.Nil(t, Cause(nil))
+	root := New("root")
+	assert.Equal(t, root, Cause(root))
+
+	b := NewBuilder("")
+	chain := WithStack(b.Wrap(
+		&causeError{"third-party", b.Errorf("middle: %w", root)},
+		"outer",
+	))
+	assert.Equal(t, root, Cause(chain))
+
+	g := Group{nil, Wrap(New("a"), "a1"), New("b")}
+	assert.Equal(t, "a", Cause(Wrap(g, "outer")).Error())
+	assert.Equal(t, "b2", Cause(&multiError{"b", []error{New("b2")}}).Error())
+}
+
+func TestRootCauses(t *testing.T) {
+	assert.Nil(t, RootCauses(nil))
+	a, c, d := New("a"), New("c"), New("d")
+	g := Group{
+		Wrap(a, "a1"),
+		&multiError{"b", []error{c, &causeError{"d1", d}}},
+	}
+	assert.Equal(t, []error{a, c, d}, RootCauses(Wrap(g, "outer")))
+	assert.Equal(t, []error{a}, RootCauses(Wrap(a, "a1")))
+}
+
+func TestPkgErrorsCause(t *testing.T) {
+	root := New("root")
+	b := NewBuilder("")
+	fb := WithFields(BuiltinBuilder, "k", 1)
+	for _, err := range []error{
+		Wrap(root, "outer"),
+		WithStack(root),
+		b.Wrap(root, "outer"),
+		b.Errorf("outer: %w", root),
+		NewLazyBuilder("").Errorf("outer: %w", root),
+		fb.Errorf("outer: %w", root),
+		fb.Wrap(root, "outer"),
+		WithFields(prefixBuilder(""), "k", 1).Wrap(root, "outer"),
+	} {
+		assert.True(t, pkgErrorsCause(err) == root, err.Error())
+	}
+
+	for _, leaf := range []error{b.Errorf("leaf"), fb.Errorf("leaf")} {
+		assert.True(t, pkgErrorsCause(leaf) == leaf, leaf.Error())
+		assert.True(t, Cause(leaf) == leaf, leaf.Error())
+	}
+	errGone := b.Errorf("gone")
+	assert.True(t, pkgErrorsCause(Wrap(errGone, "x")) == errGone)
+	assert.True(t, pkgErrorsCause(b.Errorf("x: %w", errGone)) == errGone)
+	assert.Equal(t, "outer", pkgErrorsCause(Wrap(nil, "outer")).Error())
+}